@@ -0,0 +1,48 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+// Package cmd provides the command-line interface for the application.
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the base config command.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the application configuration",
+	Long: `Config command inspects the configuration assembled from all sources.
+
+Values are resolved in the following order, later sources taking precedence:
+
+  1. built-in defaults
+  2. the config file given with --config (default .env when present)
+  3. environment variables prefixed with APP_, e.g. APP_HTTP_PORT
+  4. command-line flags, e.g. --http-port`,
+}
+
+// configShowCmd represents the command printing the effective configuration.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long:  `Print every configuration key with its effective value and the source it came from. Secrets are masked.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+
+		for _, s := range appConfig.Settings() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
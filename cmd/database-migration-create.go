@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go-template/database/migrations"
+	"go-template/pkg/logger"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// defaultMigrationsDir is where create writes new migrations when
+// --migrations-dir is not given, relative to the repository root.
+const defaultMigrationsDir = "database/migrations"
+
+// errLintFailed is returned by databaseMigrationLint when it found problems.
+var errLintFailed = errors.New("migration lint found problems")
+
+// DatabaseMigrationCreateCmd represents the command generating a new migration.
+var DatabaseMigrationCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new pair of up and down migration files",
+	Long: `Create <version>_<name>.up.sql and <version>_<name>.down.sql in --migrations-dir
+(default ` + defaultMigrationsDir + `). The version is the next sequential number, or the
+current UTC time with --numbering timestamp. It fails if the directory already
+holds two migrations with the same version.`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{withoutDatabase: ""},
+	Run: func(cmd *cobra.Command, args []string) {
+		numbering, _ := cmd.Flags().GetString("numbering")
+
+		dir := migrationsDir
+		if dir == "" {
+			dir = defaultMigrationsDir
+		}
+
+		up, down, err := migrations.Create(dir, args[0], migrations.Numbering(numbering), time.Now())
+		if err != nil {
+			logger.Fatal("Failed to create migration", zap.Error(err))
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), up)
+		fmt.Fprintln(cmd.OutOrStdout(), down)
+	},
+}
+
+// DatabaseMigrationLintCmd represents the command checking the migration files.
+var DatabaseMigrationLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check migrations for common mistakes",
+	Long: `Check the migrations for duplicate versions, missing up or down files, statements
+that cannot run inside a transaction such as CREATE INDEX CONCURRENTLY, and
+destructive statements such as DROP TABLE in up migrations.
+
+Acknowledge an intended statement with a comment line of its own right before it:
+
+  -- ` + migrations.MarkerAllowDestructive + `
+  -- ` + migrations.MarkerAllowNonTransactional + `
+
+Exits with a non-zero status when a problem is found.`,
+	Annotations: map[string]string{withoutDatabase: ""},
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := databaseMigrationLint(cmd.OutOrStdout()); err != nil {
+			logger.Fatal("Failed to lint migrations", zap.Error(err))
+		}
+	},
+}
+
+// databaseMigrationLint prints the lint problems and returns errLintFailed
+// if there are any.
+func databaseMigrationLint(w io.Writer) error {
+	if err := validateMigrationOutput(); err != nil {
+		return err
+	}
+
+	problems, err := migrations.Lint(migrations.FS(migrationsDir))
+	if err != nil {
+		return err
+	}
+
+	if migrationOutput == outputJSON {
+		if problems == nil {
+			problems = []migrations.Problem{}
+		}
+
+		if err := writeJSON(w, problems); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(w, p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errLintFailed
+	}
+
+	return nil
+}
+
+func init() {
+	DatabaseMigrationCreateCmd.Flags().String("numbering", string(migrations.Sequential), "version numbering (sequential, timestamp)")
+
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationCreateCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationLintCmd)
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go-template/database/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+// migrationPlan lists the migrations `up` would apply to the database, in
+// order, with their SQL.
+type migrationPlan struct {
+	Database    string              `json:"database"`
+	FromVersion *uint               `json:"from_version"`
+	ToVersion   *uint               `json:"to_version"`
+	Dirty       bool                `json:"dirty"`
+	Steps       []migrationPlanStep `json:"steps"`
+}
+
+// migrationPlanStep is one migration of a migrationPlan.
+type migrationPlanStep struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256"`
+	SQL     string `json:"sql"`
+}
+
+// databaseMigrationUp applies all pending migrations. With --dry-run it only
+// prints the plan; with --plan-json it also writes the plan to a file.
+func databaseMigrationUp(cmd *cobra.Command) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	planPath, _ := cmd.Flags().GetString("plan-json")
+
+	if !dryRun && planPath == "" {
+		return runMigration(cmd, "up", (*migrate.Migrate).Up)
+	}
+
+	if err := validateMigrationOutput(); err != nil {
+		return err
+	}
+
+	m, err := initializeMigration(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to initialize migration: %w", err)
+	}
+	defer closeMigration(m)
+
+	plan, err := buildMigrationPlan(m)
+	if err != nil {
+		return err
+	}
+
+	return runMigrationPlan(cmd, plan, func(w io.Writer) error {
+		return applyMigration(w, m, "up", (*migrate.Migrate).Up)
+	})
+}
+
+// runMigrationPlan writes plan as the --plan-json and --dry-run flags of cmd
+// ask and, unless it is a dry run, calls apply to migrate and report the
+// result. When the JSON plan goes to stdout, everything else goes to stderr
+// so that stdout stays a single JSON document.
+func runMigrationPlan(cmd *cobra.Command, plan migrationPlan, apply func(w io.Writer) error) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	planPath, _ := cmd.Flags().GetString("plan-json")
+
+	out := cmd.OutOrStdout()
+
+	if planPath != "" {
+		if err := writeMigrationPlan(out, planPath, plan); err != nil {
+			return err
+		}
+
+		if planPath == "-" {
+			out = cmd.ErrOrStderr()
+		}
+	}
+
+	if plan.Dirty {
+		return fmt.Errorf("database is dirty at version %s, fix the schema and run force first", formatVersion(plan.FromVersion))
+	}
+
+	if dryRun {
+		return printMigrationPlan(out, plan)
+	}
+
+	return apply(out)
+}
+
+// buildMigrationPlan reads the current version through m and collects the
+// newer migrations from the migrations source.
+func buildMigrationPlan(m *migrate.Migrate) (migrationPlan, error) {
+	status, err := readMigrationStatus(m)
+	if err != nil {
+		return migrationPlan{}, err
+	}
+
+	plan := migrationPlan{
+		Database:    fmt.Sprintf("%s@%s:%d", appConfig.DB.Name, appConfig.DB.Address, appConfig.DB.Port),
+		FromVersion: status.Version,
+		ToVersion:   status.Version,
+		Dirty:       status.Dirty,
+		Steps:       []migrationPlanStep{},
+	}
+
+	if len(status.Pending) == 0 {
+		return plan, nil
+	}
+
+	src, err := migrations.Source(migrationsDir)
+	if err != nil {
+		return migrationPlan{}, err
+	}
+	defer src.Close()
+
+	for _, p := range status.Pending {
+		r, _, err := src.ReadUp(p.Version)
+		if err != nil {
+			return migrationPlan{}, fmt.Errorf("failed to read migration %d: %w", p.Version, err)
+		}
+
+		sql, err := io.ReadAll(r)
+		r.Close()
+
+		if err != nil {
+			return migrationPlan{}, fmt.Errorf("failed to read migration %d: %w", p.Version, err)
+		}
+
+		plan.Steps = append(plan.Steps, migrationPlanStep{
+			Version: p.Version,
+			Name:    p.Name,
+			SHA256:  p.UpSHA256,
+			SQL:     string(sql),
+		})
+	}
+
+	last := status.Pending[len(status.Pending)-1].Version
+	plan.ToVersion = &last
+
+	return plan, nil
+}
+
+// writeMigrationPlan writes plan as JSON to path, or to stdout for "-".
+func writeMigrationPlan(stdout io.Writer, path string, plan migrationPlan) error {
+	if path == "-" {
+		return writeJSON(stdout, plan)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+
+	if err := writeJSON(f, plan); err != nil {
+		f.Close()
+
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+
+	return f.Close()
+}
+
+func printMigrationPlan(w io.Writer, plan migrationPlan) error {
+	if migrationOutput == outputJSON {
+		return writeJSON(w, plan)
+	}
+
+	if len(plan.Steps) == 0 {
+		fmt.Fprintf(w, "Nothing to apply, %s is at version %s\n", plan.Database, formatVersion(plan.FromVersion))
+
+		return nil
+	}
+
+	fmt.Fprintf(w, "Plan for %s: version %s to %s, %d migration(s)\n",
+		plan.Database, formatVersion(plan.FromVersion), formatVersion(plan.ToVersion), len(plan.Steps))
+
+	for _, s := range plan.Steps {
+		fmt.Fprintf(w, "\n-- %d_%s (sha256 %s)\n", s.Version, s.Name, s.SHA256)
+		fmt.Fprint(w, s.SQL)
+
+		if !strings.HasSuffix(s.SQL, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"go-template/database/migrations"
+	"go-template/pkg/logger"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Output formats of the database-migration subcommands.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// migrationOutput is the --output flag of DatabaseMigrationCmd.
+var migrationOutput string
+
+// migrationStatus is the database state reported by `status`. Version is
+// nil when no migration has been applied.
+type migrationStatus struct {
+	Version *uint                  `json:"version"`
+	Dirty   bool                   `json:"dirty"`
+	Pending []migrations.Migration `json:"pending"`
+}
+
+// migrationResult reports the effect of a command that changed the version.
+type migrationResult struct {
+	Action      string `json:"action"`
+	FromVersion *uint  `json:"from_version"`
+	Version     *uint  `json:"version"`
+	Dirty       bool   `json:"dirty"`
+}
+
+// DatabaseMigrationStatusCmd represents the command reporting the migration state.
+var DatabaseMigrationStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current migration version and pending migrations",
+	Long: `Show the schema version of the database, whether the last migration failed
+half-way (dirty), and the migrations that have not been applied yet.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := databaseMigrationStatus(cmd); err != nil {
+			logger.Fatal("Failed to read migration status", zap.Error(err))
+		}
+	},
+}
+
+// databaseMigrationStatus prints the migration state of the database.
+func databaseMigrationStatus(cmd *cobra.Command) error {
+	if err := validateMigrationOutput(); err != nil {
+		return err
+	}
+
+	m, err := initializeMigration(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to initialize migration: %w", err)
+	}
+	defer closeMigration(m)
+
+	status, err := readMigrationStatus(m)
+	if err != nil {
+		return err
+	}
+
+	return printMigrationStatus(cmd.OutOrStdout(), status)
+}
+
+// readMigrationStatus reads the database version and lists the migrations
+// newer than it.
+func readMigrationStatus(m *migrate.Migrate) (migrationStatus, error) {
+	version, dirty, err := migrationVersion(m)
+	if err != nil {
+		return migrationStatus{}, err
+	}
+
+	files, err := listMigrations()
+	if err != nil {
+		return migrationStatus{}, err
+	}
+
+	status := migrationStatus{Version: version, Dirty: dirty, Pending: []migrations.Migration{}}
+
+	for _, f := range files {
+		if version == nil || f.Version > *version {
+			status.Pending = append(status.Pending, f)
+		}
+	}
+
+	return status, nil
+}
+
+// migrationVersion returns the applied version, or nil if there is none.
+func migrationVersion(m *migrate.Migrate) (*uint, bool, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	return &version, dirty, nil
+}
+
+// listMigrations returns the available migrations ordered by version.
+func listMigrations() ([]migrations.Migration, error) {
+	src, err := migrations.Source(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return migrations.List(src)
+}
+
+// DatabaseMigrationListCmd represents the command listing the available migrations.
+var DatabaseMigrationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available migrations and their checksums",
+	Long: `List the migrations embedded in the binary, or found in --migrations-dir,
+with the SHA-256 checksums of their up and down files. It does not connect to
+the database.`,
+	Annotations: map[string]string{withoutDatabase: ""},
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := databaseMigrationList(cmd.OutOrStdout()); err != nil {
+			logger.Fatal("Failed to list migrations", zap.Error(err))
+		}
+	},
+}
+
+// databaseMigrationList prints the available migrations.
+func databaseMigrationList(w io.Writer) error {
+	if err := validateMigrationOutput(); err != nil {
+		return err
+	}
+
+	list, err := listMigrations()
+	if err != nil {
+		return err
+	}
+
+	if migrationOutput == outputJSON {
+		return writeJSON(w, list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tUP SHA256\tDOWN SHA256")
+
+	for _, m := range list {
+		down := m.DownSHA256
+		if down == "" {
+			down = "-"
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", m.Version, m.Name, m.UpSHA256, down)
+	}
+
+	return tw.Flush()
+}
+
+// runMigration performs action on m and reports the version before and after.
+func runMigration(cmd *cobra.Command, name string, action func(m *migrate.Migrate) error) error {
+	if err := validateMigrationOutput(); err != nil {
+		return err
+	}
+
+	m, err := initializeMigration(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to initialize migration: %w", err)
+	}
+	defer closeMigration(m)
+
+	return applyMigration(cmd.OutOrStdout(), m, name, action)
+}
+
+// applyMigration performs action on an initialized m and reports the result.
+func applyMigration(w io.Writer, m *migrate.Migrate, name string, action func(m *migrate.Migrate) error) error {
+	from, _, err := migrationVersion(m)
+	if err != nil {
+		return err
+	}
+
+	if err := action(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	to, dirty, err := migrationVersion(m)
+	if err != nil {
+		return err
+	}
+
+	return printMigrationResult(w, migrationResult{
+		Action:      name,
+		FromVersion: from,
+		Version:     to,
+		Dirty:       dirty,
+	})
+}
+
+func printMigrationStatus(w io.Writer, s migrationStatus) error {
+	if migrationOutput == outputJSON {
+		return writeJSON(w, s)
+	}
+
+	dirty := ""
+	if s.Dirty {
+		dirty = " (dirty)"
+	}
+
+	fmt.Fprintf(w, "Version: %s%s\n", formatVersion(s.Version), dirty)
+
+	if len(s.Pending) == 0 {
+		fmt.Fprintln(w, "Pending: none")
+
+		return nil
+	}
+
+	fmt.Fprintln(w, "Pending:")
+
+	for _, f := range s.Pending {
+		fmt.Fprintf(w, "  %d  %s\n", f.Version, f.Name)
+	}
+
+	return nil
+}
+
+func printMigrationResult(w io.Writer, r migrationResult) error {
+	if migrationOutput == outputJSON {
+		return writeJSON(w, r)
+	}
+
+	switch {
+	case r.Dirty:
+		fmt.Fprintf(w, "%s: database is dirty at version %s\n", r.Action, formatVersion(r.Version))
+	case equalVersion(r.FromVersion, r.Version):
+		fmt.Fprintf(w, "%s: no change, database at version %s\n", r.Action, formatVersion(r.Version))
+	default:
+		fmt.Fprintf(w, "%s: migrated from version %s to %s\n", r.Action, formatVersion(r.FromVersion), formatVersion(r.Version))
+	}
+
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+func formatVersion(v *uint) string {
+	if v == nil {
+		return "none"
+	}
+
+	return strconv.FormatUint(uint64(*v), 10)
+}
+
+func equalVersion(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// validateMigrationOutput checks the --output flag.
+func validateMigrationOutput() error {
+	switch migrationOutput {
+	case outputText, outputJSON:
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format %q, want %s or %s", migrationOutput, outputText, outputJSON)
+}
@@ -5,116 +5,229 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
+	"go-template/database/migrations"
 	"go-template/internal/clients/db"
 	"go-template/pkg/logger"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file" // Required for file-based migrations
 	"github.com/spf13/cobra"
 
 	"go.uber.org/zap"
 )
 
-const (
-	migrationsPath = "file://database/migrations"
-	dbDriver       = "postgres"
-)
+const dbDriver = "postgres"
+
+// migrationsDir is the --migrations-dir flag. When empty, the migrations
+// embedded in the binary are used.
+var migrationsDir string
 
 // DatabaseMigrationCmd represents the root database-migration command.
 var DatabaseMigrationCmd = &cobra.Command{
 	Use:   "database-migration",
 	Short: "Manage database migrations",
 	Long: `Database migration command provides functionality to manage database schema migrations.
-It supports both applying (up) and reverting (down) migrations.`,
+It supports applying (up) and reverting (down) all migrations, moving to a given
+version (goto) or by a number of migrations (steps), inspecting the current state
+(status) and recovering from a failed migration (force).
+
+The migrations are embedded in the binary; --migrations-dir reads the .sql
+files from a directory instead, e.g. while writing a new migration.
+
+Every subcommand prints its result as text, or as JSON with --output json.`,
 }
 
 // DatabaseMigrationUpCmd represents the command to apply migrations.
 var DatabaseMigrationUpCmd = &cobra.Command{
 	Use:   "up",
 	Short: "Apply database migrations",
-	Long:  `Apply all pending database migrations to update the schema to the latest version.`,
-	Run: func(_ *cobra.Command, _ []string) {
-		if err := databaseMigrationUp(); err != nil {
+	Long: `Apply all pending database migrations to update the schema to the latest version.
+
+With --dry-run the pending migrations and their SQL are printed, based on the
+current version of the database, and nothing is applied. --plan-json writes
+the same plan as JSON to a file for CI to archive; with "-" it goes to stdout
+and the rest of the output to stderr.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := databaseMigrationUp(cmd); err != nil {
 			logger.Fatal("Failed to apply migrations", zap.Error(err))
 		}
-		logger.Info("Successfully applied all migrations")
 	},
 }
 
-// databaseMigrationUp handles the database migration up operation.
-func databaseMigrationUp() error {
-	m, err := initializeMigration()
-	if err != nil {
-		return fmt.Errorf("failed to initialize migration: %w", err)
-	}
+// DatabaseMigrationDownCmd represents the command to revert migrations.
+var DatabaseMigrationDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert database migrations",
+	Long: `Revert all applied database migrations to downgrade the schema to its base version.
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			logger.Info("No migrations to apply - database is up to date")
+This drops every table created by the migrations. It asks for confirmation
+unless --yes is given; use "steps -- -1" to revert a single migration.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := databaseMigrationDown(cmd); err != nil {
+			logger.Fatal("Failed to revert migrations", zap.Error(err))
+		}
+	},
+}
+
+// databaseMigrationDown asks for confirmation and reverts all migrations.
+func databaseMigrationDown(cmd *cobra.Command) error {
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !yes {
+		prompt := fmt.Sprintf("This reverts ALL migrations on database %q at %s:%d. Type \"yes\" to continue: ",
+			appConfig.DB.Name, appConfig.DB.Address, appConfig.DB.Port)
 
-			return nil
+		if !confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), prompt) {
+			return errors.New("aborted, pass --yes to revert without confirmation")
 		}
+	}
+
+	return runMigration(cmd, "down", (*migrate.Migrate).Down)
+}
+
+// confirm writes prompt to w and reports whether the answer read from r is "yes".
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprint(w, prompt)
 
-		return fmt.Errorf("failed to apply migrations: %w", err)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+
+		return false
 	}
 
-	return nil
+	return strings.TrimSpace(answer) == "yes"
 }
 
-// DatabaseMigrationDownCmd represents the command to revert migrations.
-var DatabaseMigrationDownCmd = &cobra.Command{
-	Use:   "down",
-	Short: "Revert database migrations",
-	Long:  `Revert all applied database migrations to downgrade the schema to its base version.`,
-	Run: func(_ *cobra.Command, _ []string) {
-		if err := databaseMigrationDown(); err != nil {
-			logger.Fatal("Failed to revert migrations", zap.Error(err))
+// DatabaseMigrationGotoCmd represents the command to migrate to a version.
+var DatabaseMigrationGotoCmd = &cobra.Command{
+	Use:   "goto <version>",
+	Short: "Migrate up or down to a version",
+	Long:  `Apply or revert migrations until the schema is at the given version.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			logger.Fatal("Invalid version", zap.String("version", args[0]), zap.Error(err))
+		}
+
+		err = runMigration(cmd, "goto", func(m *migrate.Migrate) error {
+			return m.Migrate(uint(version))
+		})
+		if err != nil {
+			logger.Fatal("Failed to migrate to version", zap.Uint64("version", version), zap.Error(err))
 		}
-		logger.Info("Successfully reverted all migrations")
 	},
 }
 
-// databaseMigrationDown handles the database migration down operation.
-func databaseMigrationDown() error {
-	m, err := initializeMigration()
-	if err != nil {
-		return fmt.Errorf("failed to initialize migration: %w", err)
-	}
-
-	if err := m.Down(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			logger.Info("No migrations to revert - database is at base version")
+// DatabaseMigrationStepsCmd represents the command to apply or revert n migrations.
+var DatabaseMigrationStepsCmd = &cobra.Command{
+	Use:   "steps <n>",
+	Short: "Apply or revert n migrations",
+	Long: `Apply the next n migrations when n is positive, or revert the last -n
+migrations when n is negative. Separate a negative n from the flags with --:
+
+  go-template database-migration steps -- -1`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n == 0 {
+			logger.Fatal("Invalid number of steps, want a non-zero integer", zap.String("steps", args[0]))
+		}
 
-			return nil
+		err = runMigration(cmd, "steps", func(m *migrate.Migrate) error {
+			return m.Steps(n)
+		})
+		if err != nil {
+			logger.Fatal("Failed to migrate steps", zap.Int("steps", n), zap.Error(err))
 		}
+	},
+}
 
-		return fmt.Errorf("failed to revert migrations: %w", err)
-	}
+// DatabaseMigrationForceCmd represents the command to set the version without migrating.
+var DatabaseMigrationForceCmd = &cobra.Command{
+	Use:   "force <version>",
+	Short: "Set the migration version without running migrations",
+	Long: `Record the given version as applied and clear the dirty flag without running
+any migration. Use it after fixing the schema by hand when a migration failed
+half-way; -1 records that no migration is applied.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < database.NilVersion {
+			logger.Fatal("Invalid version", zap.String("version", args[0]))
+		}
 
-	return nil
+		err = runMigration(cmd, "force", func(m *migrate.Migrate) error {
+			return m.Force(version)
+		})
+		if err != nil {
+			logger.Fatal("Failed to force version", zap.Int("version", version), zap.Error(err))
+		}
+	},
 }
 
-// initializeMigration creates and configures a new migration instance.
-func initializeMigration() (*migrate.Migrate, error) {
-	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+// initializeMigration connects to the database and creates a new migration
+// instance. Closing the instance closes the database handle.
+func initializeMigration(ctx context.Context) (*migrate.Migrate, error) {
+	conn, err := db.OpenPrimary(ctx, appConfig.DB)
 	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithInstance(conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+
 		return nil, fmt.Errorf("failed to create postgres driver: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(migrationsPath, dbDriver, driver)
+	src, err := migrations.Source(migrationsDir)
+	if err != nil {
+		driver.Close()
+
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance(migrations.SourceName, src, dbDriver, driver)
 	if err != nil {
+		src.Close()
+		driver.Close()
+
 		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
 
 	return m, nil
 }
 
+// closeMigration releases the migration source and database handle.
+func closeMigration(m *migrate.Migrate) {
+	if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+		logger.Error("Failed to close migration", zap.NamedError("source", srcErr), zap.NamedError("database", dbErr))
+	}
+}
+
 func init() {
+	DatabaseMigrationCmd.PersistentFlags().StringVarP(&migrationOutput, "output", "o", outputText, "output format (text, json)")
+	DatabaseMigrationCmd.PersistentFlags().StringVar(&migrationsDir, "migrations-dir", "", "read migrations from this directory instead of the embedded ones")
+	DatabaseMigrationUpCmd.Flags().Bool("dry-run", false, "print the pending migrations and their SQL without applying them")
+	DatabaseMigrationUpCmd.Flags().String("plan-json", "", "write the migration plan as JSON to this file (- for stdout)")
+	DatabaseMigrationDownCmd.Flags().BoolP("yes", "y", false, "revert without asking for confirmation")
+
 	DatabaseMigrationCmd.AddCommand(DatabaseMigrationUpCmd)
 	DatabaseMigrationCmd.AddCommand(DatabaseMigrationDownCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationStatusCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationListCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationGotoCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationStepsCmd)
+	DatabaseMigrationCmd.AddCommand(DatabaseMigrationForceCmd)
 	rootCmd.AddCommand(DatabaseMigrationCmd)
 }
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"go-template/database/migrations"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"yes\n", true},
+		{"  yes  \n", true},
+		{"yes", true},
+		{"y\n", false},
+		{"no\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+
+			assert.Equal(t, tt.want, confirm(strings.NewReader(tt.input), &out, "Continue? "))
+			assert.True(t, strings.HasPrefix(out.String(), "Continue? "))
+		})
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	version := uint(1)
+	status := migrationStatus{
+		Version: &version,
+		Pending: []migrations.Migration{{Version: 2, Name: "widen_users_password", UpSHA256: "abc"}},
+	}
+
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{outputText, "Version: 1\nPending:\n  2  widen_users_password\n"},
+		{outputJSON, `{"version":1,"dirty":false,"pending":[{"version":2,"name":"widen_users_password","up_sha256":"abc"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			migrationOutput = tt.output
+			t.Cleanup(func() { migrationOutput = outputText })
+
+			var out bytes.Buffer
+			require.NoError(t, printMigrationStatus(&out, status))
+
+			if tt.output == outputJSON {
+				assert.JSONEq(t, tt.want, out.String())
+			} else {
+				assert.Equal(t, tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestPrintMigrationResult(t *testing.T) {
+	one, two := uint(1), uint(2)
+
+	tests := []struct {
+		name   string
+		result migrationResult
+		want   string
+	}{
+		{"migrated", migrationResult{Action: "up", FromVersion: &one, Version: &two}, "up: migrated from version 1 to 2\n"},
+		{"from base", migrationResult{Action: "up", Version: &two}, "up: migrated from version none to 2\n"},
+		{"no change", migrationResult{Action: "up", FromVersion: &two, Version: &two}, "up: no change, database at version 2\n"},
+		{"dirty", migrationResult{Action: "steps", FromVersion: &one, Version: &two, Dirty: true}, "steps: database is dirty at version 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, printMigrationResult(&out, tt.result))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestPrintMigrationPlan(t *testing.T) {
+	one, two := uint(1), uint(2)
+
+	tests := []struct {
+		name string
+		plan migrationPlan
+		want string
+	}{
+		{
+			name: "pending",
+			plan: migrationPlan{
+				Database:    "example@localhost:5432",
+				FromVersion: &one,
+				ToVersion:   &two,
+				Steps: []migrationPlanStep{
+					{Version: 2, Name: "widen_users_password", SHA256: "abc", SQL: "ALTER TABLE users;"},
+				},
+			},
+			want: "Plan for example@localhost:5432: version 1 to 2, 1 migration(s)\n" +
+				"\n-- 2_widen_users_password (sha256 abc)\nALTER TABLE users;\n",
+		},
+		{
+			name: "up to date",
+			plan: migrationPlan{Database: "example@localhost:5432", FromVersion: &two, ToVersion: &two},
+			want: "Nothing to apply, example@localhost:5432 is at version 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, printMigrationPlan(&out, tt.plan))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestRunMigrationPlan_stdout(t *testing.T) {
+	one, two := uint(1), uint(2)
+	plan := migrationPlan{
+		Database:    "example@localhost:5432",
+		FromVersion: &one,
+		ToVersion:   &two,
+		Steps:       []migrationPlanStep{{Version: 2, Name: "widen_users_password", SHA256: "abc", SQL: "ALTER TABLE users;"}},
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		stderr string
+	}{
+		{"dry run", true, "Plan for example@localhost:5432: version 1 to 2"},
+		{"applied", false, "up: migrated from version 1 to 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("dry-run", tt.dryRun, "")
+			cmd.Flags().String("plan-json", "-", "")
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			applied := false
+			err := runMigrationPlan(cmd, plan, func(w io.Writer) error {
+				applied = true
+
+				return printMigrationResult(w, migrationResult{Action: "up", FromVersion: &one, Version: &two})
+			})
+			require.NoError(t, err)
+			assert.Equal(t, !tt.dryRun, applied)
+
+			var got migrationPlan
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &got), "stdout holds only the JSON plan")
+			assert.Equal(t, plan, got)
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func TestDatabaseMigrationList_withoutDatabaseConfig(t *testing.T) {
+	// No config file, and no APP_ variable set.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	for _, kv := range os.Environ() {
+		if key, _, _ := strings.Cut(kv, "="); strings.HasPrefix(key, "APP_") {
+			t.Setenv(key, "")
+		}
+	}
+
+	var out bytes.Buffer
+
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"database-migration", "list"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, out.String(), "widen_users_password")
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"go-template/database/fixtures"
+	"go-template/database/seed"
+	"go-template/internal/clients/db"
+	"go-template/pkg/logger"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const defaultFixtureSet = "dev"
+
+// DatabaseSeedCmd represents the command loading fixtures into the database.
+var DatabaseSeedCmd = &cobra.Command{
+	Use:   "database-seed [set]",
+	Short: "Load a fixture set into the database",
+	Long: `Load the fixtures of a named set (dev, demo or test; default ` + defaultFixtureSet + `) into the
+database. Each fixture file fills one table; tables are loaded after the tables
+listed in their depends_on, and rows are upserted on their key columns, so
+seeding twice leaves the database unchanged. Everything runs in one transaction.
+
+With --truncate the tables of the set are emptied first, dropping rows that
+are not in the fixtures. The fixtures are embedded in the binary;
+--fixtures-dir reads the sets from a directory instead.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		set := defaultFixtureSet
+		if len(args) == 1 {
+			set = args[0]
+		}
+
+		if err := databaseSeed(cmd, set); err != nil {
+			logger.Fatal("Failed to seed database", zap.String("set", set), zap.Error(err))
+		}
+	},
+}
+
+// databaseSeed loads set into the configured database and prints the number
+// of rows per table.
+func databaseSeed(cmd *cobra.Command, set string) error {
+	truncate, _ := cmd.Flags().GetBool("truncate")
+	dir, _ := cmd.Flags().GetString("fixtures-dir")
+
+	var fsys fs.FS = fixtures.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	}
+
+	result, err := seedDatabase(cmd.Context(), fsys, set, seed.Options{Truncate: truncate})
+	if err != nil {
+		return err
+	}
+
+	printSeedResult(cmd.OutOrStdout(), result)
+
+	return nil
+}
+
+func seedDatabase(ctx context.Context, fsys fs.FS, set string, opts seed.Options) (seed.Result, error) {
+	conn, err := db.Open(ctx, appConfig.DB)
+	if err != nil {
+		return seed.Result{}, err
+	}
+	defer conn.Close()
+
+	return seed.Seed(ctx, conn, fsys, set, opts)
+}
+
+func printSeedResult(w io.Writer, result seed.Result) {
+	for _, t := range result.Tables {
+		fmt.Fprintf(w, "%s: %d rows\n", t.Table, t.Rows)
+	}
+}
+
+func init() {
+	DatabaseSeedCmd.Flags().Bool("truncate", false, "empty the tables of the set before loading it")
+	DatabaseSeedCmd.Flags().String("fixtures-dir", "", "read fixture sets from this directory instead of the embedded ones")
+
+	rootCmd.AddCommand(DatabaseSeedCmd)
+}
@@ -7,12 +7,20 @@ package cmd
 import (
 	"os"
 
+	"go-template/internal/config"
 	"go-template/pkg/logger"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
+// appConfig is the typed configuration loaded before any subcommand runs.
+var appConfig *config.Config
+
+// withoutDatabase is set in the Annotations of commands that never connect
+// to the database, so that they run without the DB_ settings.
+const withoutDatabase = "without-database"
+
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
 	Use:   "go-template",
@@ -26,6 +34,28 @@ var rootCmd = &cobra.Command{
 - Graceful shutdown handling
 - Health checks and monitoring endpoints`,
 	Version: "1.0.0",
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := config.Init(func() (*config.Config, error) {
+			return loadConfig(cmd)
+		})
+		if err != nil {
+			return err
+		}
+
+		appConfig = cfg
+
+		if err := logger.SetLevel(cfg.App.LogLevel); err != nil {
+			return err
+		}
+
+		config.OnChange([]string{config.LOG_LEVEL}, func(_, next *config.Config) {
+			if err := logger.SetLevel(next.App.LogLevel); err != nil {
+				logger.Error("Failed to change log level", zap.Error(err))
+			}
+		})
+
+		return nil
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -40,12 +70,61 @@ func Execute() {
 	}
 }
 
+// defaultConfigFile is read when --config is not given and the file exists.
+const defaultConfigFile = ".env"
+
+// configFile returns the config file to read, or "" when there is none.
+func configFile(cmd *cobra.Command) string {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		return path
+	}
+
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		return defaultConfigFile
+	}
+
+	return ""
+}
+
+// loadConfig layers the configuration sources in precedence order: built-in
+// defaults, the config file, APP_-prefixed environment variables, then flags.
+// The DB_ settings are not required for commands annotated withoutDatabase.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path := configFile(cmd)
+
+	var sources []config.Source
+
+	if path != "" {
+		file, err := config.NewFileSource(path)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, file)
+	}
+
+	sources = append(sources, config.EnvSource{}, config.NewFlagSource(cmd.Flags()))
+
+	if _, ok := cmd.Annotations[withoutDatabase]; ok {
+		return config.LoadWithoutDatabase(sources...)
+	}
+
+	return config.Load(sources...)
+}
+
 func init() {
 	// Configure persistent flags that will be inherited by all subcommands
-	rootCmd.PersistentFlags().StringP("config", "c", "", "config file path (default is .env)")
+	rootCmd.PersistentFlags().StringP("config", "c", "", "config file path, YAML, TOML, JSON or .env (default is .env)")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "enable debug mode")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable verbose logging")
 
+	// Flags overriding configuration keys; --http-port sets HTTP_PORT and so on
+	rootCmd.PersistentFlags().String("host", "", "host address to bind to")
+	rootCmd.PersistentFlags().Int("http-port", 0, "port for the HTTP server")
+	rootCmd.PersistentFlags().Int("grpc-port", 0, "port for the gRPC server")
+	rootCmd.PersistentFlags().String("log-level", "", "log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().String("env", "", "deployment environment")
+
 	// Bind flags to environment variables
 	if err := rootCmd.PersistentFlags().MarkHidden("debug"); err != nil {
 		logger.Error("Failed to mark debug flag as hidden", zap.Error(err))
@@ -7,10 +7,14 @@ package cmd
 import (
 	"context"
 
+	"go-template/database/migrations"
+	"go-template/internal/clients/db"
 	"go-template/internal/config"
+	"go-template/internal/readiness"
 	"go-template/pkg/logger"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	serverGRPC "go-template/server/grpc"
 	serverHTTP "go-template/server/http"
@@ -20,7 +24,13 @@ import (
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start HTTP or gRPC server",
-	Long:  `Serve command allows you to start either an HTTP API server or a gRPC server.`,
+	Long: `Serve command allows you to start either an HTTP API server or a gRPC server.
+
+With --migrate-on-start (or MIGRATE_ON_START=true) pending database migrations
+are applied at startup under a PostgreSQL advisory lock, so only one of several
+replicas starting together migrates while the others wait. The readiness checks
+(/ready and the gRPC health service) fail until the migrations are applied, and
+keep failing if the schema is dirty or newer than the binary.`,
 }
 
 // serveHTTPCmd represents the HTTP server command.
@@ -29,6 +39,8 @@ var serveHTTPCmd = &cobra.Command{
 	Short: "Start the HTTP API Server",
 	Long:  `Start an HTTP API Server with the configured host and port from environment variables.`,
 	Run: func(cmd *cobra.Command, _ []string) {
+		watchConfig(cmd)
+		startMigrations(cmd.Context())
 		serveHTTP(cmd.Context())
 	},
 }
@@ -37,10 +49,7 @@ var serveHTTPCmd = &cobra.Command{
 func serveHTTP(ctx context.Context) {
 	defer logger.Sync() // flushes buffer, if any
 
-	host := config.Get(config.HOST)
-	port := config.Get(config.HTTP_PORT)
-
-	serverHTTP.CreateHTPPServer(ctx, host, port, nil)
+	serverHTTP.CreateHTPPServer(ctx, appConfig, nil)
 }
 
 // serveGRPCCmd represents the gRPC server command.
@@ -49,6 +58,8 @@ var serveGRPCCmd = &cobra.Command{
 	Short: "Start the gRPC Server",
 	Long:  `Start a gRPC Server with the configured host and ports from environment variables.`,
 	Run: func(cmd *cobra.Command, _ []string) {
+		watchConfig(cmd)
+		startMigrations(cmd.Context())
 		serveGRPC(cmd.Context())
 	},
 }
@@ -57,14 +68,54 @@ var serveGRPCCmd = &cobra.Command{
 func serveGRPC(ctx context.Context) {
 	defer logger.Sync() // flushes buffer, if any
 
-	host := config.Get(config.HOST)
-	grpcPort := config.Get(config.GRPC_PORT)
-	httpPort := config.Get(config.HTTP_PORT)
+	serverGRPC.CreateGRPCServer(ctx, appConfig)
+}
+
+// startMigrations applies pending migrations in the background when
+// MIGRATE_ON_START is set, holding the process not ready until they are done.
+// A failure leaves the process running but not ready, so it receives no
+// traffic and its logs stay available.
+func startMigrations(ctx context.Context) {
+	if !appConfig.DB.MigrateOnStart {
+		return
+	}
+
+	readiness.Set(false, "applying database migrations")
+
+	go func() {
+		if err := migrateOnStart(ctx); err != nil {
+			logger.Error("Database migration on startup failed, staying not ready", zap.Error(err))
+			readiness.Set(false, "database migration failed: "+err.Error())
+
+			return
+		}
 
-	serverGRPC.CreateGRPCServer(ctx, host, grpcPort, httpPort)
+		readiness.Set(true, "")
+	}()
+}
+
+// migrateOnStart connects to the database and applies pending migrations.
+func migrateOnStart(ctx context.Context) error {
+	conn, err := db.OpenPrimary(ctx, appConfig.DB)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return migrations.UpLocked(ctx, conn, appConfig.DB.MigrateLockTimeout)
+}
+
+// watchConfig reloads the configuration when the config file changes or the
+// process receives SIGHUP.
+func watchConfig(cmd *cobra.Command) {
+	if err := config.Watch(cmd.Context(), configFile(cmd)); err != nil {
+		logger.Error("Failed to watch configuration, live reload disabled", zap.Error(err))
+	}
 }
 
 func init() {
+	serveCmd.PersistentFlags().Bool("migrate-on-start", false, "apply pending database migrations before reporting ready")
+
 	serveCmd.AddCommand(serveHTTPCmd)
 	serveCmd.AddCommand(serveGRPCCmd)
 	rootCmd.AddCommand(serveCmd)
@@ -0,0 +1,11 @@
+// Package fixtures embeds the named fixture sets loaded by database-seed.
+// Each set is a directory of YAML or JSON files, one per table; see
+// package seed for the file format.
+package fixtures
+
+import "embed"
+
+// FS holds the dev, demo and test fixture sets.
+//
+//go:embed dev demo test
+var FS embed.FS
@@ -0,0 +1,5 @@
+-- Hashes longer than 50 characters, which every argon2id hash is, cannot be
+-- kept in the narrower column. They are replaced with "!", which matches no
+-- password: the users concerned cannot log in until their password is reset.
+UPDATE users SET password = '!' WHERE length(password) > 50;
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR (50);
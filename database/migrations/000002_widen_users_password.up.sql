@@ -0,0 +1,2 @@
+-- Password hashes (argon2id PHC strings, legacy bcrypt) do not fit in 50 characters.
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR (255);
@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Numbering selects how Create numbers a new migration.
+type Numbering string
+
+const (
+	// Sequential numbers migrations 000001, 000002, ...
+	Sequential Numbering = "sequential"
+	// Timestamp numbers migrations by their UTC creation time, e.g.
+	// 20250102150405, which avoids collisions between branches.
+	Timestamp Numbering = "timestamp"
+)
+
+const (
+	sequentialWidth = 6
+	timestampFormat = "20060102150405"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+const header = `-- Migration %s (%s), created %s.
+--
+-- Statements in this file run in one implicit transaction. Intentional data
+-- loss and statements that cannot run in a transaction are acknowledged with
+-- a lint:allow comment line right before the statement; database-migration
+-- lint names the one to add.
+
+`
+
+// Create writes an empty up and down migration named name to dir and returns
+// their paths. It refuses to run when dir already holds two migrations with
+// the same version, or when the new version is taken.
+func Create(dir, name string, numbering Numbering, now time.Time) (up, down string, err error) {
+	identifier := strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if identifier == "" {
+		return "", "", fmt.Errorf("invalid migration name %q", name)
+	}
+
+	versions, err := scanVersions(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	if dups := duplicateVersions(versions); len(dups) > 0 {
+		return "", "", fmt.Errorf("duplicate migration versions in %s: %s", dir, strings.Join(dups, ", "))
+	}
+
+	var version string
+
+	switch numbering {
+	case Sequential:
+		var last uint64
+		for v := range versions {
+			last = max(last, v)
+		}
+
+		version = fmt.Sprintf("%0*d", sequentialWidth, last+1)
+	case Timestamp:
+		version = now.UTC().Format(timestampFormat)
+	default:
+		return "", "", fmt.Errorf("unknown numbering %q, want %s or %s", numbering, Sequential, Timestamp)
+	}
+
+	n, _ := strconv.ParseUint(version, 10, 64)
+	if _, ok := versions[n]; ok {
+		return "", "", fmt.Errorf("migration version %s already exists in %s", version, dir)
+	}
+
+	base := version + "_" + identifier
+	created := now.UTC().Format(time.RFC3339)
+
+	up = filepath.Join(dir, base+"."+string(source.Up)+".sql")
+	down = filepath.Join(dir, base+"."+string(source.Down)+".sql")
+
+	if err := writeNew(up, fmt.Sprintf(header, base, source.Up, created)); err != nil {
+		return "", "", err
+	}
+
+	if err := writeNew(down, fmt.Sprintf(header, base, source.Down, created)); err != nil {
+		os.Remove(up)
+
+		return "", "", err
+	}
+
+	return up, down, nil
+}
+
+// writeNew writes content to path, failing if path exists.
+func writeNew(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// scanVersions maps each migration version in fsys to the identifiers used
+// with it. Files that are not migrations are skipped.
+func scanVersions(fsys fs.FS) (map[uint64][]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	versions := make(map[uint64][]string)
+
+	for _, e := range entries {
+		m, err := source.Parse(e.Name())
+		if e.IsDir() || err != nil || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		v := uint64(m.Version)
+		if !slices.Contains(versions[v], m.Identifier) {
+			versions[v] = append(versions[v], m.Identifier)
+		}
+	}
+
+	return versions, nil
+}
+
+// duplicateVersions describes every version used by more than one name.
+func duplicateVersions(versions map[uint64][]string) []string {
+	var dups []string
+
+	for v, names := range versions {
+		if len(names) > 1 {
+			dups = append(dups, fmt.Sprintf("%d (%s)", v, strings.Join(names, ", ")))
+		}
+	}
+
+	slices.Sort(dups)
+
+	return dups
+}
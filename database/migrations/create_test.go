@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	t.Run("sequential", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "000007_a.up.sql"), nil, 0o600))
+
+		up, down, err := Create(dir, "Add Orders table", Sequential, now)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "000008_add_orders_table.up.sql"), up)
+		assert.Equal(t, filepath.Join(dir, "000008_add_orders_table.down.sql"), down)
+
+		b, err := os.ReadFile(up)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "-- Migration 000008_add_orders_table (up), created 2025-01-02T15:04:05Z.")
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		dir := t.TempDir()
+
+		up, _, err := Create(dir, "orders", Timestamp, now)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "20250102150405_orders.up.sql"), up)
+
+		_, _, err = Create(dir, "again", Timestamp, now)
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("duplicate versions", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "000002_a.up.sql"), nil, 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "000002_b.up.sql"), nil, 0o600))
+
+		_, _, err := Create(dir, "c", Sequential, now)
+		assert.ErrorContains(t, err, "duplicate migration versions")
+		assert.ErrorContains(t, err, "2 (a, b)")
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, _, err := Create(t.TempDir(), "--", Sequential, now)
+		assert.ErrorContains(t, err, "invalid migration name")
+	})
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Lint rules reported in Problem.Rule.
+const (
+	RuleInvalidName      = "invalid-name"
+	RuleDuplicateVersion = "duplicate-version"
+	RuleMissingUp        = "missing-up"
+	RuleMissingDown      = "missing-down"
+	RuleNonTransactional = "non-transactional"
+	RuleDestructive      = "destructive"
+)
+
+// Markers that acknowledge a statement flagged by Lint. A marker is a
+// comment line of its own, "-- " followed by the marker, placed in the
+// statement or between it and the previous one.
+const (
+	MarkerAllowDestructive      = "lint:allow-destructive"
+	MarkerAllowNonTransactional = "lint:allow-non-transactional"
+)
+
+// Problem is a lint finding. Line is 0 for findings about a whole file.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s (%s)", p.File, p.Line, p.Message, p.Rule)
+	}
+
+	return fmt.Sprintf("%s: %s (%s)", p.File, p.Message, p.Rule)
+}
+
+type statementRule struct {
+	rule    string
+	marker  string
+	pattern *regexp.Regexp
+	message string
+}
+
+// allowed reports whether the statement of sql preceding offset, as
+// delimited in code, carries the rule's marker line.
+func (r statementRule) allowed(sql, code string, offset int) bool {
+	start := strings.LastIndex(code[:offset], ";") + 1
+
+	for _, line := range strings.Split(sql[start:offset], "\n") {
+		if strings.TrimSpace(line) == "-- "+r.marker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statementRules are checked against the SQL with comments removed.
+// Destructive statements are only flagged in up migrations, since undoing
+// a migration is expected to remove what it created.
+var statementRules = []statementRule{
+	{RuleNonTransactional, MarkerAllowNonTransactional,
+		regexp.MustCompile(`(?i)\b(CREATE|DROP)\s+(UNIQUE\s+)?INDEX\s+CONCURRENTLY\b`), "%s cannot run inside a transaction"},
+	{RuleNonTransactional, MarkerAllowNonTransactional,
+		regexp.MustCompile(`(?i)\bREINDEX\b[^;]*\bCONCURRENTLY\b`), "%s cannot run inside a transaction"},
+	{RuleNonTransactional, MarkerAllowNonTransactional,
+		regexp.MustCompile(`(?i)\b(VACUUM|ALTER\s+SYSTEM|(CREATE|DROP)\s+DATABASE)\b`), "%s cannot run inside a transaction"},
+	{RuleDestructive, MarkerAllowDestructive,
+		regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|SCHEMA|COLUMN)|TRUNCATE)\b`), "%s destroys data"},
+}
+
+var sqlComments = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+
+// Lint checks the migrations in fsys for version collisions, missing up or
+// down files, statements that cannot run inside the transaction wrapping
+// a migration and destructive statements without an explicit marker.
+func Lint(fsys fs.FS) ([]Problem, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	type pair struct {
+		up, down []string
+	}
+
+	pairs := make(map[uint]*pair)
+
+	var problems []Problem
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			problems = append(problems, Problem{File: e.Name(), Rule: RuleInvalidName,
+				Message: "file name does not match <version>_<name>.up.sql or <version>_<name>.down.sql"})
+
+			continue
+		}
+
+		p := pairs[m.Version]
+		if p == nil {
+			p = &pair{}
+			pairs[m.Version] = p
+		}
+
+		if m.Direction == source.Up {
+			p.up = append(p.up, e.Name())
+		} else {
+			p.down = append(p.down, e.Name())
+		}
+
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+
+		problems = append(problems, lintSQL(e.Name(), string(b), m.Direction)...)
+	}
+
+	for _, p := range pairs {
+		switch {
+		case len(p.up) > 1 || len(p.down) > 1:
+			files := append(append([]string{}, p.up...), p.down...)
+			sort.Strings(files)
+			problems = append(problems, Problem{File: files[0], Rule: RuleDuplicateVersion,
+				Message: "version is also used by " + strings.Join(files[1:], ", ")})
+		case len(p.up) == 0:
+			problems = append(problems, Problem{File: p.down[0], Rule: RuleMissingUp, Message: "no up migration"})
+		case len(p.down) == 0:
+			problems = append(problems, Problem{File: p.up[0], Rule: RuleMissingDown, Message: "no down migration"})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems, nil
+}
+
+// lintSQL applies statementRules to one migration file.
+func lintSQL(file, sql string, direction source.Direction) []Problem {
+	// Blank out comments, keeping newlines so offsets map to the same lines.
+	// Bytes are replaced one for one, so offsets into code also index sql.
+	code := sqlComments.ReplaceAllStringFunc(sql, func(c string) string {
+		b := []byte(c)
+		for i := range b {
+			if b[i] != '\n' {
+				b[i] = ' '
+			}
+		}
+
+		return string(b)
+	})
+
+	var problems []Problem
+
+	for _, r := range statementRules {
+		if r.rule == RuleDestructive && direction == source.Down {
+			continue
+		}
+
+		for _, loc := range r.pattern.FindAllStringIndex(code, -1) {
+			if r.allowed(sql, code, loc[0]) {
+				continue
+			}
+
+			stmt := strings.ToUpper(strings.Join(strings.Fields(code[loc[0]:loc[1]]), " "))
+			problems = append(problems, Problem{
+				File:    file,
+				Line:    strings.Count(code[:loc[0]], "\n") + 1,
+				Rule:    r.rule,
+				Message: fmt.Sprintf(r.message, stmt) + fmt.Sprintf(`; add "-- %s" if intended`, r.marker),
+			})
+		}
+	}
+
+	return problems
+}
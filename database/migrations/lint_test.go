@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_users.up.sql":   {Data: []byte("CREATE TABLE users (id serial);\n")},
+		"000001_users.down.sql": {Data: []byte("DROP TABLE users;\n")},
+		"000002_index.up.sql": {Data: []byte("-- speeds up lookups\n" +
+			"CREATE INDEX\n  concurrently users_email_idx ON users (email);\n")},
+		"000003_cleanup.up.sql":   {Data: []byte("/* DROP TABLE in a comment is fine */\nALTER TABLE users DROP COLUMN email;\n")},
+		"000003_cleanup.down.sql": {Data: []byte("ALTER TABLE users ADD COLUMN email text;\n")},
+		"000004_purge.up.sql":     {Data: []byte("-- lint:allow-destructive\nTRUNCATE users;\n")},
+		"000004_purge.down.sql":   {Data: []byte("")},
+		"000007_mixed.up.sql": {Data: []byte("-- lint:allow-destructive\nTRUNCATE sessions;\n" +
+			"-- not here: lint:allow-destructive\nDROP TABLE users;\n")},
+		"000007_mixed.down.sql":  {Data: []byte("")},
+		"000005_a.up.sql":        {Data: []byte("")},
+		"000005_b.up.sql":        {Data: []byte("")},
+		"000006_orphan.down.sql": {Data: []byte("")},
+		"notes.sql":              {Data: []byte("")},
+		"migrations.go":          {Data: []byte("package migrations\n")},
+	}
+
+	problems, err := Lint(fsys)
+	require.NoError(t, err)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+
+	assert.Equal(t, []string{
+		`000002_index.up.sql: no down migration (missing-down)`,
+		`000002_index.up.sql:2: CREATE INDEX CONCURRENTLY cannot run inside a transaction; add "-- lint:allow-non-transactional" if intended (non-transactional)`,
+		`000003_cleanup.up.sql:2: DROP COLUMN destroys data; add "-- lint:allow-destructive" if intended (destructive)`,
+		`000005_a.up.sql: version is also used by 000005_b.up.sql (duplicate-version)`,
+		`000006_orphan.down.sql: no up migration (missing-up)`,
+		`000007_mixed.up.sql:4: DROP TABLE destroys data; add "-- lint:allow-destructive" if intended (destructive)`,
+		`notes.sql: file name does not match <version>_<name>.up.sql or <version>_<name>.down.sql (invalid-name)`,
+	}, got)
+}
+
+func TestLint_embedded(t *testing.T) {
+	problems, err := Lint(FS(""))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestLint_created(t *testing.T) {
+	dir := t.TempDir()
+
+	up, _, err := Create(dir, "drop users", Sequential, time.Now())
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(up, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("DROP TABLE users;\nCREATE INDEX CONCURRENTLY users_email_idx ON users (email);\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	problems, err := Lint(os.DirFS(dir))
+	require.NoError(t, err)
+
+	var rules []string
+	for _, p := range problems {
+		assert.Equal(t, filepath.Base(up), p.File)
+		rules = append(rules, p.Rule)
+	}
+
+	assert.Equal(t, []string{RuleDestructive, RuleNonTransactional}, rules, "the header written by Create acknowledges nothing")
+}
@@ -0,0 +1,106 @@
+// Package migrations embeds the SQL schema migrations into the binary so it
+// can migrate a database without access to the source tree.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// SourceName is the name golang-migrate reports for the migrations source.
+const SourceName = "iofs"
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration describes one migration and the SHA-256 of its files. DownSHA256
+// is empty when the migration has no down file.
+type Migration struct {
+	Version    uint   `json:"version"`
+	Name       string `json:"name"`
+	UpSHA256   string `json:"up_sha256"`
+	DownSHA256 string `json:"down_sha256,omitempty"`
+}
+
+// FS returns the embedded migrations, or the files in dir when dir is not
+// empty.
+func FS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+
+	return files
+}
+
+// Source returns a golang-migrate source serving the embedded migrations,
+// or the .sql files in dir instead when dir is not empty.
+func Source(dir string) (source.Driver, error) {
+	src, err := iofs.New(FS(dir), ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	return src, nil
+}
+
+// List returns the migrations in src ordered by version.
+func List(src source.Driver) ([]Migration, error) {
+	var list []Migration
+
+	version, err := src.First()
+	for err == nil {
+		m := Migration{Version: version}
+
+		r, name, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+
+		m.Name = name
+
+		if m.UpSHA256, readErr = checksum(r); readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+
+		r, _, readErr = src.ReadDown(version)
+		switch {
+		case readErr == nil:
+			if m.DownSHA256, readErr = checksum(r); readErr != nil {
+				return nil, fmt.Errorf("failed to read down migration %d: %w", version, readErr)
+			}
+		case !errors.Is(readErr, fs.ErrNotExist):
+			return nil, fmt.Errorf("failed to read down migration %d: %w", version, readErr)
+		}
+
+		list = append(list, m)
+
+		version, err = src.Next(version)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return list, nil
+}
+
+// checksum returns the hex SHA-256 of r's contents and closes r.
+func checksum(r io.ReadCloser) (string, error) {
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList_embedded(t *testing.T) {
+	src, err := Source("")
+	require.NoError(t, err)
+	defer src.Close()
+
+	list, err := List(src)
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	assert.Equal(t, uint(1), list[0].Version)
+	assert.Equal(t, "create_users_table", list[0].Name)
+
+	for _, m := range list {
+		assert.Len(t, m.UpSHA256, 64, "migration %d", m.Version)
+		assert.Len(t, m.DownSHA256, 64, "migration %d", m.Version)
+	}
+}
+
+func TestList_dir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_a.up.sql"), []byte("SELECT 1;\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000003_b.up.sql"), []byte("SELECT 3;\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000003_b.down.sql"), []byte(""), 0o600))
+
+	src, err := Source(dir)
+	require.NoError(t, err)
+	defer src.Close()
+
+	list, err := List(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "a", UpSHA256: "b4e0497804e46e0a0b0b8c31975b062152d551bac49c3c2e80932567b4085dcd"},
+		{Version: 3, Name: "b", UpSHA256: "fa4a71571fc2071c8ba7b9fa042ad3267b4f134515497aecc339df06ffd3725d", DownSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}, list)
+}
@@ -0,0 +1,162 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"go-template/pkg/logger"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"go.uber.org/zap"
+)
+
+// Errors returned by UpLocked when the schema cannot be migrated safely.
+var (
+	ErrDirty         = errors.New("database schema is dirty")
+	ErrSchemaTooNew  = errors.New("database schema is newer than this binary")
+	ErrLockNotGained = errors.New("timed out waiting for the migration lock")
+)
+
+// lockKey identifies the advisory lock held while migrating on startup.
+var lockKey = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("go-template/migrations"))
+
+	return int64(h.Sum64())
+}()
+
+// UpLocked applies the embedded migrations to db while holding a PostgreSQL
+// advisory lock, so that when several replicas start at once one of them
+// migrates and the others wait up to lockTimeout for it to finish. It does
+// not touch a schema that is dirty or at a version newer than the newest
+// embedded migration, and reports ErrDirty or ErrSchemaTooNew instead.
+func UpLocked(ctx context.Context, db *sql.DB, lockTimeout time.Duration) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection for the migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	if err := acquireLock(ctx, conn, lockTimeout); err != nil {
+		return err
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	src, err := Source("")
+	if err != nil {
+		return err
+	}
+
+	list, err := List(src)
+	if err != nil {
+		src.Close()
+
+		return err
+	}
+
+	// A connection of its own keeps the driver from closing db on Close.
+	migrateConn, err := db.Conn(ctx)
+	if err != nil {
+		src.Close()
+
+		return fmt.Errorf("failed to get a connection for migrating: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, migrateConn, &postgres.Config{})
+	if err != nil {
+		src.Close()
+		migrateConn.Close()
+
+		return fmt.Errorf("failed to create postgres driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance(SourceName, src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+
+		return fmt.Errorf("failed to create migration instance: %w", err)
+	}
+
+	defer func() {
+		if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+			logger.Error("Failed to close migration", zap.NamedError("source", srcErr), zap.NamedError("database", dbErr))
+		}
+	}()
+
+	return up(m, list)
+}
+
+// acquireLock takes the migration lock on conn, waiting up to timeout when
+// another instance holds it.
+func acquireLock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	if locked {
+		return nil
+	}
+
+	logger.Info("Waiting for another instance to finish migrating", zap.Duration("timeout", timeout))
+
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		if errors.Is(lockCtx.Err(), context.DeadlineExceeded) {
+			return ErrLockNotGained
+		}
+
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	return nil
+}
+
+// up checks the schema version against the known migrations and applies
+// the pending ones.
+func up(m *migrate.Migrate, list []Migration) error {
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("%w at version %d, fix it with database-migration force", ErrDirty, version)
+	}
+
+	var latest uint
+	if len(list) > 0 {
+		latest = list[len(list)-1].Version
+	}
+
+	if version > latest {
+		return fmt.Errorf("%w: database is at version %d, newest known migration is %d", ErrSchemaTooNew, version, latest)
+	}
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			logger.Info("Database schema is up to date", zap.Uint("version", version))
+
+			return nil
+		}
+
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	logger.Info("Applied database migrations", zap.Uint("from", version), zap.Uint("to", latest))
+
+	return nil
+}
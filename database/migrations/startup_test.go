@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"1_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"2_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"2_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	tests := []struct {
+		name    string
+		version int
+		dirty   bool
+		wantErr error
+		wantRun []string
+	}{
+		{name: "empty", version: -1, wantRun: []string{"CREATE TABLE a ();", "CREATE TABLE b ();"}},
+		{name: "pending", version: 1, wantRun: []string{"CREATE TABLE b ();"}},
+		{name: "up to date", version: 2, wantRun: []string{}},
+		{name: "dirty", version: 2, dirty: true, wantErr: ErrDirty},
+		{name: "newer", version: 3, wantErr: ErrSchemaTooNew},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := iofs.New(fsys, ".")
+			require.NoError(t, err)
+
+			list, err := List(src)
+			require.NoError(t, err)
+
+			driver, err := stub.WithInstance(nil, &stub.Config{})
+			require.NoError(t, err)
+
+			db := driver.(*stub.Stub)
+			db.CurrentVersion, db.IsDirty = tt.version, tt.dirty
+
+			m, err := migrate.NewWithInstance(SourceName, src, "stub", driver)
+			require.NoError(t, err)
+
+			err = up(m, list)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, db.MigrationSequence, "nothing may run")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRun, db.MigrationSequence)
+			assert.Equal(t, 2, db.CurrentVersion)
+		})
+	}
+}
@@ -0,0 +1,336 @@
+// Package seed loads fixture files into the database. It backs the
+// database-seed command and can be used directly from integration tests:
+//
+//	err := seed.Seed(ctx, database, fixtures.FS, "test", seed.Options{Truncate: true})
+//
+// A fixture set is a directory holding one YAML (.yaml, .yml) or JSON
+// (.json) file per table:
+//
+//	table: users
+//	key: [username]      # columns identifying a row, used for upserts
+//	depends_on: []       # tables that must be seeded first
+//	rows:
+//	  - username: alice
+//	    email: alice@example.com
+//
+// Rows are upserted on their key columns, so seeding twice leaves the
+// tables unchanged. Tables are seeded after the tables they depend on.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"go-template/internal/clients/db"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the content of one fixture file.
+type Fixture struct {
+	Table     string           `json:"table" yaml:"table"`
+	Key       []string         `json:"key" yaml:"key"`
+	DependsOn []string         `json:"depends_on" yaml:"depends_on"`
+	Rows      []map[string]any `json:"rows" yaml:"rows"`
+
+	file string
+}
+
+// Options configures Seed and Apply.
+type Options struct {
+	// Truncate empties the tables of the set, restarting their identity
+	// sequences, before loading them.
+	Truncate bool
+}
+
+// Result reports how many rows were upserted into each table, in the order
+// the tables were seeded.
+type Result struct {
+	Tables []TableResult
+}
+
+// TableResult is the outcome for one table.
+type TableResult struct {
+	Table string
+	Rows  int
+}
+
+// Sets lists the fixture sets in fsys, i.e. its top-level directories.
+func Sets(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var sets []string
+
+	for _, e := range entries {
+		if e.IsDir() {
+			sets = append(sets, e.Name())
+		}
+	}
+
+	return sets, nil
+}
+
+// Load reads the fixtures of set from fsys and returns them in dependency
+// order.
+func Load(fsys fs.FS, set string) ([]Fixture, error) {
+	entries, err := fs.ReadDir(fsys, set)
+	if err != nil {
+		return nil, fmt.Errorf("unknown fixture set %q: %w", set, err)
+	}
+
+	var fixtures []Fixture
+
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		name := path.Join(set, e.Name())
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		f, err := parse(b, ext == ".json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		f.file = name
+
+		fixtures = append(fixtures, f)
+	}
+
+	return order(fixtures)
+}
+
+func parse(b []byte, isJSON bool) (Fixture, error) {
+	var f Fixture
+
+	if isJSON {
+		if err := json.Unmarshal(b, &f); err != nil {
+			return Fixture{}, err
+		}
+	} else if err := yaml.Unmarshal(b, &f); err != nil {
+		return Fixture{}, err
+	}
+
+	if f.Table == "" {
+		return Fixture{}, fmt.Errorf("table is required")
+	}
+
+	if len(f.Key) == 0 {
+		return Fixture{}, fmt.Errorf("key is required to upsert rows")
+	}
+
+	for i, row := range f.Rows {
+		for _, k := range f.Key {
+			if _, ok := row[k]; !ok {
+				return Fixture{}, fmt.Errorf("row %d: key column %s is missing", i+1, k)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// order sorts fixtures so that every table comes after the tables it
+// depends on. Independent tables keep alphabetical order.
+func order(fixtures []Fixture) ([]Fixture, error) {
+	byTable := make(map[string]Fixture, len(fixtures))
+
+	for _, f := range fixtures {
+		if prev, ok := byTable[f.Table]; ok {
+			return nil, fmt.Errorf("table %s is seeded by both %s and %s", f.Table, prev.file, f.file)
+		}
+
+		byTable[f.Table] = f
+	}
+
+	tables := make([]string, 0, len(byTable))
+	for t := range byTable {
+		tables = append(tables, t)
+	}
+
+	sort.Strings(tables)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[string]int, len(tables))
+	sorted := make([]Fixture, 0, len(tables))
+
+	var visit func(table string, path []string) error
+	visit = func(table string, path []string) error {
+		switch state[table] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("fixture dependency cycle: %s", strings.Join(append(path, table), " -> "))
+		}
+
+		f, ok := byTable[table]
+		if !ok {
+			return fmt.Errorf("%s depends on %s, which has no fixture in the set", path[len(path)-1], table)
+		}
+
+		state[table] = visiting
+
+		for _, dep := range f.DependsOn {
+			if err := visit(dep, append(path, table)); err != nil {
+				return err
+			}
+		}
+
+		state[table] = done
+		sorted = append(sorted, f)
+
+		return nil
+	}
+
+	for _, t := range tables {
+		if err := visit(t, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// Apply loads fixtures, which must be in dependency order as returned by
+// Load, running the statements on q. Use a transaction as q to load all or
+// nothing.
+func Apply(ctx context.Context, q db.Querier, fixtures []Fixture, opts Options) (Result, error) {
+	var result Result
+
+	if opts.Truncate && len(fixtures) > 0 {
+		tables := make([]string, len(fixtures))
+		for i, f := range fixtures {
+			tables[i] = pq.QuoteIdentifier(f.Table)
+		}
+
+		if _, err := q.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+			return Result{}, fmt.Errorf("failed to truncate tables: %w", err)
+		}
+	}
+
+	for _, f := range fixtures {
+		for i, row := range f.Rows {
+			query, args, err := upsert(f.Table, f.Key, row)
+			if err != nil {
+				return Result{}, fmt.Errorf("%s row %d: %w", f.file, i+1, err)
+			}
+
+			if _, err := q.ExecContext(ctx, query, args...); err != nil {
+				return Result{}, fmt.Errorf("failed to seed %s row %d: %w", f.file, i+1, err)
+			}
+		}
+
+		result.Tables = append(result.Tables, TableResult{Table: f.Table, Rows: len(f.Rows)})
+	}
+
+	return result, nil
+}
+
+// Seed loads the fixture set from fsys into d in a single transaction.
+func Seed(ctx context.Context, d *db.DB, fsys fs.FS, set string, opts Options) (Result, error) {
+	fixtures, err := Load(fsys, set)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+
+	err = d.WithTx(ctx, nil, func(tx db.Tx) error {
+		result, err = Apply(ctx, tx, fixtures, opts)
+
+		return err
+	})
+
+	return result, err
+}
+
+// upsert builds an INSERT ... ON CONFLICT statement for row, updating the
+// non-key columns of an existing row.
+func upsert(table string, key []string, row map[string]any) (string, []any, error) {
+	columns := make([]string, 0, len(row))
+	for c := range row {
+		columns = append(columns, c)
+	}
+
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]any, len(columns))
+
+	var updates []string
+
+	for i, c := range columns {
+		v, err := value(row[c])
+		if err != nil {
+			return "", nil, fmt.Errorf("column %s: %w", c, err)
+		}
+
+		quoted[i] = pq.QuoteIdentifier(c)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = v
+
+		if !slices.Contains(key, c) {
+			updates = append(updates, quoted[i]+" = EXCLUDED."+quoted[i])
+		}
+	}
+
+	keyCols := make([]string, len(key))
+	for i, k := range key {
+		keyCols[i] = pq.QuoteIdentifier(k)
+	}
+
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		pq.QuoteIdentifier(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "),
+		strings.Join(keyCols, ", "), action)
+
+	return query, args, nil
+}
+
+// value converts a decoded fixture value to a query argument. Lists and
+// objects are passed as JSON for json and jsonb columns.
+func value(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	case float64:
+		// JSON numbers; keep integers integral.
+		if v == float64(int64(v)) {
+			return int64(v), nil
+		}
+
+		return v, nil
+	}
+
+	return v, nil
+}
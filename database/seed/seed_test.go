@@ -0,0 +1,181 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"go-template/database/fixtures"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dev/users.yaml": {Data: []byte(`
+table: users
+key: [username]
+rows:
+  - username: alice
+    email: alice@example.com
+`)},
+		"dev/orders.json": {Data: []byte(`{
+  "table": "orders",
+  "key": ["id"],
+  "depends_on": ["users"],
+  "rows": [{"id": 1, "user": "alice", "items": [{"sku": "a", "qty": 2}]}]
+}`)},
+		"dev/README.md": {Data: []byte("not a fixture")},
+	}
+
+	got, err := Load(fsys, "dev")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "users", got[0].Table)
+	assert.Equal(t, "orders", got[1].Table)
+	assert.Equal(t, []string{"users"}, got[1].DependsOn)
+	assert.Equal(t, "alice@example.com", got[0].Rows[0]["email"])
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "missing table",
+			files: fstest.MapFS{"s/a.yaml": {Data: []byte("key: [id]\n")}},
+			want:  "table is required",
+		},
+		{
+			name:  "missing key",
+			files: fstest.MapFS{"s/a.yaml": {Data: []byte("table: a\n")}},
+			want:  "key is required",
+		},
+		{
+			name:  "row without key column",
+			files: fstest.MapFS{"s/a.yaml": {Data: []byte("table: a\nkey: [id]\nrows:\n  - name: x\n")}},
+			want:  "key column id is missing",
+		},
+		{
+			name: "duplicate table",
+			files: fstest.MapFS{
+				"s/a.yaml": {Data: []byte("table: a\nkey: [id]\n")},
+				"s/b.json": {Data: []byte(`{"table": "a", "key": ["id"]}`)},
+			},
+			want: "seeded by both",
+		},
+		{
+			name:  "unknown dependency",
+			files: fstest.MapFS{"s/a.yaml": {Data: []byte("table: a\nkey: [id]\ndepends_on: [b]\n")}},
+			want:  "a depends on b",
+		},
+		{
+			name: "cycle",
+			files: fstest.MapFS{
+				"s/a.yaml": {Data: []byte("table: a\nkey: [id]\ndepends_on: [b]\n")},
+				"s/b.yaml": {Data: []byte("table: b\nkey: [id]\ndepends_on: [a]\n")},
+			},
+			want: "cycle: a -> b -> a",
+		},
+		{
+			name:  "unknown set",
+			files: fstest.MapFS{"s/a.yaml": {Data: []byte("table: a\nkey: [id]\n")}},
+			want:  "unknown fixture set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := "s"
+			if tt.name == "unknown set" {
+				set = "missing"
+			}
+
+			_, err := Load(tt.files, set)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	query, args, err := upsert("users", []string{"username"}, map[string]any{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"profile":  map[string]any{"theme": "dark"},
+		"age":      float64(30),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "users" ("age", "email", "profile", "username") VALUES ($1, $2, $3, $4) `+
+		`ON CONFLICT ("username") DO UPDATE SET "age" = EXCLUDED."age", "email" = EXCLUDED."email", `+
+		`"profile" = EXCLUDED."profile"`, query)
+	assert.Equal(t, []any{int64(30), "alice@example.com", `{"theme":"dark"}`, "alice"}, args)
+
+	query, _, err = upsert("tags", []string{"name"}, map[string]any{"name": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "tags" ("name") VALUES ($1) ON CONFLICT ("name") DO NOTHING`, query)
+}
+
+// recorder is a db.Querier recording the statements it is given.
+type recorder struct {
+	queries []string
+	fail    bool
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	if r.fail {
+		return nil, errors.New("boom")
+	}
+
+	return nil, nil
+}
+
+func (r *recorder) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *recorder) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	set := []Fixture{
+		{Table: "users", Key: []string{"id"}, Rows: []map[string]any{{"id": 1}, {"id": 2}}},
+		{Table: "orders", Key: []string{"id"}, DependsOn: []string{"users"}, Rows: []map[string]any{{"id": 1}}},
+	}
+
+	r := &recorder{}
+	result, err := Apply(context.Background(), r, set, Options{Truncate: true})
+	require.NoError(t, err)
+	assert.Equal(t, []TableResult{{"users", 2}, {"orders", 1}}, result.Tables)
+	require.Len(t, r.queries, 4)
+	assert.Equal(t, `TRUNCATE "users", "orders" RESTART IDENTITY CASCADE`, r.queries[0])
+
+	r = &recorder{}
+	_, err = Apply(context.Background(), r, set, Options{})
+	require.NoError(t, err)
+	assert.Len(t, r.queries, 3)
+
+	_, err = Apply(context.Background(), &recorder{fail: true}, set, Options{})
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestEmbeddedSets(t *testing.T) {
+	sets, err := Sets(fixtures.FS)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demo", "dev", "test"}, sets)
+
+	for _, set := range sets {
+		t.Run(set, func(t *testing.T) {
+			got, err := Load(fixtures.FS, set)
+			require.NoError(t, err)
+			assert.NotEmpty(t, got)
+		})
+	}
+}
@@ -1,32 +1,208 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go-template/internal/config"
 	"go-template/pkg/logger"
 
-	_ "github.com/lib/pq" // Register postgres driver
-
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
-var DB *sql.DB
+const (
+	driverName = "postgres"
+
+	pingTimeout      = 5 * time.Second
+	pingInitialDelay = 250 * time.Millisecond
+	pingMaxDelay     = 5 * time.Second
+)
+
+// DB is a pooled handle to the PostgreSQL database. Create it with Open and
+// pass it to the components that need it.
+//
+// The embedded *sql.DB is the primary; its statement methods, prepared
+// statements included, are shadowed by instrumented ones, and transactions
+// are started with WithTx. When read replicas are configured, Reader and
+// read-only transactions started with WithTx use them instead.
+type DB struct {
+	*sql.DB
+
+	obs      observer
+	replicas []*replica
+	balancer string
+	next     atomic.Uint64
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Open connects to PostgreSQL, configures the connection pool and pings the
+// server until it answers or cfg.ConnectTimeout elapses, so unreachable
+// servers and bad credentials are reported at startup rather than on the
+// first query. Read replicas in cfg.Replicas get a pool each with the same
+// settings; they are health checked in the background until Close.
+//
+// Every statement gets a span and a latency observation, and is logged when
+// it takes longer than cfg.SlowQueryThreshold. The pool statistics are
+// exported as gauges until Close.
+func Open(ctx context.Context, cfg config.Database) (*DB, error) {
+	sqlDB, err := OpenPrimary(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DB{DB: sqlDB, obs: observer{pool: "primary", slowQuery: cfg.SlowQueryThreshold}, stop: make(chan struct{})}
+
+	if err := d.openReplicas(ctx, cfg); err != nil {
+		sqlDB.Close()
+
+		return nil, err
+	}
+
+	d.wg.Add(1)
 
-func init() {
-	dataSource := fmt.Sprintf(
-		"host=%s port=5432 dbname=%s user=%s  password=%s sslmode=disable",
-		config.Get(config.DB_ADDRESS),
-		config.Get(config.DB_NAME),
-		config.Get(config.DB_USER),
-		config.Get(config.DB_PW),
-	)
+	go d.watchStats()
 
-	db, err := sql.Open("postgres", dataSource)
+	return d, nil
+}
+
+// OpenPrimary connects to the primary like Open, but without replicas,
+// instrumentation or background goroutines. It suits short-lived work such
+// as migrations; closing the returned *sql.DB releases everything.
+func OpenPrimary(ctx context.Context, cfg config.Database) (*sql.DB, error) {
+	sqlDB, err := sql.Open(driverName, DSN(cfg))
 	if err != nil {
-		logger.Fatal("Failed to initialize database", zap.Error(err))
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	configurePool(sqlDB, cfg)
+
+	if err := ping(ctx, sqlDB, cfg.ConnectTimeout); err != nil {
+		sqlDB.Close()
+
+		return nil, fmt.Errorf("failed to connect to postgres at %s:%d/%s as %s: %w",
+			cfg.Address, cfg.Port, cfg.Name, cfg.User, err)
+	}
+
+	return sqlDB, nil
+}
+
+// Close stops the background health checks and statistics and closes the
+// replica and primary pools.
+func (d *DB) Close() error {
+	if d.stop != nil {
+		close(d.stop)
+		d.wg.Wait()
+		d.stop = nil
+	}
+
+	d.closeReplicas()
+
+	return d.DB.Close()
+}
+
+func configurePool(sqlDB *sql.DB, cfg config.Database) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// DSN builds a lib/pq key=value connection string from cfg, quoting values
+// so passwords may contain spaces and quotes.
+func DSN(cfg config.Database) string {
+	params := map[string]string{
+		"host":             cfg.Address,
+		"port":             strconv.Itoa(cfg.Port),
+		"dbname":           cfg.Name,
+		"user":             cfg.User,
+		"password":         cfg.Password.Value(),
+		"sslmode":          cfg.SSLMode,
+		"sslrootcert":      cfg.SSLRootCert,
+		"application_name": cfg.ApplicationName,
+	}
+
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+quoteDSNValue(params[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	return "'" + r.Replace(v) + "'"
+}
+
+// ping retries with exponential backoff until the server answers, the
+// timeout elapses or the error is one that retrying cannot fix.
+func ping(ctx context.Context, sqlDB *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := pingInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		pingCtx, pingCancel := context.WithTimeout(ctx, pingTimeout)
+		err := sqlDB.PingContext(pingCtx)
+		pingCancel()
+
+		if err == nil {
+			return nil
+		}
+
+		if !retryablePingError(err) {
+			return err
+		}
+
+		logger.Warn("Database not ready, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, pingMaxDelay)
+	}
+}
+
+// retryablePingError reports whether err may go away on its own, as opposed
+// to authentication and configuration errors.
+func retryablePingError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return true
+	}
+
+	switch pqErr.Code.Class() {
+	case "28", // invalid authorization specification, e.g. a bad password
+		"3D": // invalid catalog name, i.e. the database does not exist
+		return false
 	}
 
-	DB = db
+	return true
 }
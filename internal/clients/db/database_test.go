@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-template/internal/config"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDSN(t *testing.T) {
+	cfg := config.Database{
+		Address:         "db.internal",
+		Port:            6432,
+		Name:            "app",
+		User:            "svc",
+		Password:        `it's a \secret`,
+		SSLMode:         "verify-full",
+		SSLRootCert:     "/etc/ssl/root.crt",
+		ApplicationName: "go-template",
+	}
+
+	assert.Equal(t,
+		`application_name='go-template' dbname='app' host='db.internal' password='it\'s a \\secret' `+
+			`port='6432' sslmode='verify-full' sslrootcert='/etc/ssl/root.crt' user='svc'`,
+		DSN(cfg))
+
+	cfg.SSLRootCert = ""
+	assert.NotContains(t, DSN(cfg), "sslrootcert")
+}
+
+func TestRetryablePingError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network error", err: errors.New("dial tcp: connection refused"), want: true},
+		{name: "server starting up", err: &pq.Error{Code: "57P03"}, want: true},
+		{name: "bad password", err: &pq.Error{Code: "28P01"}, want: false},
+		{name: "unknown database", err: &pq.Error{Code: "3D000"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryablePingError(tt.err))
+		})
+	}
+}
+
+func TestOpen_unreachable(t *testing.T) {
+	cfg := config.Database{
+		Address:        "127.0.0.1",
+		Port:           1,
+		Name:           "app",
+		User:           "svc",
+		SSLMode:        "disable",
+		MaxOpenConns:   1,
+		ConnectTimeout: 600 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := Open(context.Background(), cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to postgres at 127.0.0.1:1/app as svc")
+	assert.Contains(t, err.Error(), "gave up after")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
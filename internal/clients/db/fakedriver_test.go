@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver that records the statements it is
+// given and fails them on demand, for testing without a PostgreSQL server.
+type fakeDriver struct {
+	mu         sync.Mutex
+	statements []string
+	// fail returns the error for a statement, or nil to let it succeed.
+	fail func(stmt string) error
+}
+
+var fakeDriverSeq atomic.Int64
+
+// newFakeDB returns a DB backed by a fresh fakeDriver.
+func newFakeDB(t *testing.T) (*DB, *fakeDriver) {
+	t.Helper()
+
+	d := &fakeDriver{}
+	name := fmt.Sprintf("fake-%d", fakeDriverSeq.Add(1))
+	sql.Register(name, d)
+
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return &DB{DB: sqlDB}, d
+}
+
+func (d *fakeDriver) record(stmt string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.statements = append(d.statements, stmt)
+
+	if d.fail != nil {
+		return d.fail(stmt)
+	}
+
+	return nil
+}
+
+// log returns the recorded statements and resets the log.
+func (d *fakeDriver) log() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.statements
+	d.statements = nil
+
+	return s
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	return c.d.record("PING")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	stmt := "BEGIN"
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		stmt += " ISOLATION LEVEL " + sql.IsolationLevel(opts.Isolation).String()
+	}
+
+	if opts.ReadOnly {
+		stmt += " READ ONLY"
+	}
+
+	if err := c.d.record(stmt); err != nil {
+		return nil, err
+	}
+
+	return &fakeTx{d: c.d}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.record(query); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.record(query); err != nil {
+		return nil, err
+	}
+
+	return fakeRows{}, nil
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if err := s.d.record(s.query); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if err := s.d.record(s.query); err != nil {
+		return nil, err
+	}
+
+	return fakeRows{}, nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (t *fakeTx) Commit() error {
+	return t.d.record("COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.d.record("ROLLBACK")
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string           { return nil }
+func (fakeRows) Close() error                { return nil }
+func (fakeRows) Next(_ []driver.Value) error { return io.EOF }
@@ -0,0 +1,447 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"go-template/pkg/logger"
+	"go-template/pkg/metrics"
+	"go-template/pkg/tracer"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// statsInterval is how often the connection pool gauges are updated.
+const statsInterval = 15 * time.Second
+
+var (
+	queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", []string{"operation"},
+		"Duration of database statements by operation (SELECT, INSERT, ...).")
+
+	poolOpen = metrics.NewGaugeVec("db_pool_open_connections", []string{"pool"},
+		"Established connections, in use or idle, by pool (primary or replica).")
+	poolInUse = metrics.NewGaugeVec("db_pool_in_use_connections", []string{"pool"},
+		"Connections currently in use, by pool.")
+	poolIdle = metrics.NewGaugeVec("db_pool_idle_connections", []string{"pool"},
+		"Idle connections, by pool.")
+	poolWaitCount = metrics.NewGaugeVec("db_pool_wait_count", []string{"pool"},
+		"Total number of connections waited for since the pool was opened, by pool.")
+	poolWaitDuration = metrics.NewGaugeVec("db_pool_wait_duration_seconds", []string{"pool"},
+		"Total time spent waiting for a connection since the pool was opened, by pool.")
+)
+
+// observer records a span, the latency and, above the slow query threshold,
+// a log entry for every statement run on one pool.
+type observer struct {
+	pool      string
+	slowQuery time.Duration
+}
+
+// observe runs a statement through run, which receives the context carrying
+// the statement's span.
+func (o observer) observe(ctx context.Context, query string, run func(ctx context.Context) error) {
+	statement := sanitizeSQL(query)
+	operation := sqlOperation(statement)
+
+	ctx, span := tracer.StartSpan(ctx, "db.query",
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.statement", statement),
+		attribute.String("db.pool", o.pool),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := run(ctx)
+	elapsed := time.Since(start)
+
+	queryDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if o.slowQuery > 0 && elapsed >= o.slowQuery {
+		logger.Warn("Slow database query",
+			zap.String("statement", statement),
+			zap.Duration("duration", elapsed),
+			zap.String("pool", o.pool),
+			zap.Error(err))
+	}
+}
+
+func (o observer) exec(ctx context.Context, q Querier, query string, args []any) (res sql.Result, err error) {
+	o.observe(ctx, query, func(ctx context.Context) error {
+		res, err = q.ExecContext(ctx, query, args...)
+
+		return err
+	})
+
+	return res, err
+}
+
+func (o observer) query(ctx context.Context, q Querier, query string, args []any) (rows *sql.Rows, err error) {
+	o.observe(ctx, query, func(ctx context.Context) error {
+		rows, err = q.QueryContext(ctx, query, args...)
+
+		return err
+	})
+
+	return rows, err
+}
+
+// queryRow runs the query right away; the span does not cover the Scan.
+func (o observer) queryRow(ctx context.Context, q Querier, query string, args []any) (row *sql.Row) {
+	o.observe(ctx, query, func(ctx context.Context) error {
+		row = q.QueryRowContext(ctx, query, args...)
+
+		return row.Err()
+	})
+
+	return row
+}
+
+// ExecContext runs a statement on the primary.
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.obs.exec(ctx, d.DB, query, args)
+}
+
+// QueryContext runs a query on the primary. Use Reader to read from a replica.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.obs.query(ctx, d.DB, query, args)
+}
+
+// QueryRowContext runs a query on the primary. Use Reader to read from a replica.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.obs.queryRow(ctx, d.DB, query, args)
+}
+
+// Exec is ExecContext with the background context. It shadows the method of
+// the embedded *sql.DB, like Query, QueryRow and Prepare, so that no
+// statement escapes the instrumentation.
+func (d *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return d.ExecContext(context.Background(), query, args...)
+}
+
+// Query is QueryContext with the background context.
+func (d *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.QueryContext(context.Background(), query, args...)
+}
+
+// QueryRow is QueryRowContext with the background context.
+func (d *DB) QueryRow(query string, args ...any) *sql.Row {
+	return d.QueryRowContext(context.Background(), query, args...)
+}
+
+// PrepareContext prepares a statement on the primary. Its executions are
+// instrumented like the other statements.
+func (d *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	stmt, err := d.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stmt{Stmt: stmt, query: query, obs: d.obs}, nil
+}
+
+// Prepare is PrepareContext with the background context.
+func (d *DB) Prepare(query string) (*Stmt, error) {
+	return d.PrepareContext(context.Background(), query)
+}
+
+// Stmt is a prepared statement of a DB.
+type Stmt struct {
+	*sql.Stmt
+	query string
+	obs   observer
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args ...any) (res sql.Result, err error) {
+	s.obs.observe(ctx, s.query, func(ctx context.Context) error {
+		res, err = s.Stmt.ExecContext(ctx, args...)
+
+		return err
+	})
+
+	return res, err
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args ...any) (rows *sql.Rows, err error) {
+	s.obs.observe(ctx, s.query, func(ctx context.Context) error {
+		rows, err = s.Stmt.QueryContext(ctx, args...)
+
+		return err
+	})
+
+	return rows, err
+}
+
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...any) (row *sql.Row) {
+	s.obs.observe(ctx, s.query, func(ctx context.Context) error {
+		row = s.Stmt.QueryRowContext(ctx, args...)
+
+		return row.Err()
+	})
+
+	return row
+}
+
+func (s *Stmt) Exec(args ...any) (sql.Result, error) {
+	return s.ExecContext(context.Background(), args...)
+}
+
+func (s *Stmt) Query(args ...any) (*sql.Rows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+func (s *Stmt) QueryRow(args ...any) *sql.Row {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// pool is an instrumented *sql.DB, used for the replicas.
+type pool struct {
+	*sql.DB
+	obs observer
+}
+
+func (p *pool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.obs.exec(ctx, p.DB, query, args)
+}
+
+func (p *pool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.obs.query(ctx, p.DB, query, args)
+}
+
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.obs.queryRow(ctx, p.DB, query, args)
+}
+
+func (t *tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.obs.exec(ctx, t.Tx, query, args)
+}
+
+func (t *tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.obs.query(ctx, t.Tx, query, args)
+}
+
+func (t *tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.obs.queryRow(ctx, t.Tx, query, args)
+}
+
+// watchStats updates the connection pool gauges every statsInterval until
+// Close.
+func (d *DB) watchStats() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		d.recordStats()
+
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *DB) recordStats() {
+	recordPoolStats(d.obs.pool, d.Stats())
+
+	for _, r := range d.replicas {
+		recordPoolStats(r.db.obs.pool, r.db.Stats())
+	}
+}
+
+func recordPoolStats(name string, s sql.DBStats) {
+	poolOpen.WithLabelValues(name).Set(float64(s.OpenConnections))
+	poolInUse.WithLabelValues(name).Set(float64(s.InUse))
+	poolIdle.WithLabelValues(name).Set(float64(s.Idle))
+	poolWaitCount.WithLabelValues(name).Set(float64(s.WaitCount))
+	poolWaitDuration.WithLabelValues(name).Set(s.WaitDuration.Seconds())
+}
+
+// sqlOperation returns the leading keyword of a sanitized statement, such as
+// SELECT, or OTHER when it does not start with one.
+func sqlOperation(statement string) string {
+	word, _, _ := strings.Cut(statement, " ")
+
+	for _, c := range word {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return "OTHER"
+		}
+	}
+
+	if word == "" {
+		return "OTHER"
+	}
+
+	return strings.ToUpper(word)
+}
+
+// sanitizeSQL replaces the string and number literals in query with ?, drops
+// comments and collapses whitespace, so that statements can be recorded
+// without the values they may carry. Placeholders such as $1 are kept.
+func sanitizeSQL(query string) string {
+	var b strings.Builder
+
+	b.Grow(len(query))
+
+	space := false
+	emit := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+
+		space = false
+
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+
+			space = true
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+
+			space = true
+		case c == '\'':
+			i = skipString(query, i+1, false)
+
+			emit("?")
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+			i = skipString(query, i+2, true)
+
+			emit("?")
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+
+			emit(query[i : i+end+2])
+			i += end + 2
+		case c == '$':
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+
+			if j > i+1 {
+				emit(query[i:j]) // placeholder
+
+				i = j
+
+				continue
+			}
+
+			for j < len(query) && isIdent(query[j]) {
+				j++
+			}
+
+			if j < len(query) && query[j] == '$' {
+				tag := query[i : j+1]
+				if end := strings.Index(query[j+1:], tag); end >= 0 {
+					i = j + 1 + end + len(tag)
+				} else {
+					i = len(query)
+				}
+
+				emit("?")
+
+				continue
+			}
+
+			emit("$")
+			i++
+		case isDigit(c) || c == '.' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+
+			if j < len(query) && (query[j] == 'e' || query[j] == 'E') {
+				j++
+				if j < len(query) && (query[j] == '+' || query[j] == '-') {
+					j++
+				}
+
+				for j < len(query) && isDigit(query[j]) {
+					j++
+				}
+			}
+
+			emit("?")
+			i = j
+		case isIdent(c):
+			j := i
+			for j < len(query) && (isIdent(query[j]) || query[j] == '$') {
+				j++
+			}
+
+			emit(query[i:j])
+			i = j
+		default:
+			emit(query[i : i+1])
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// skipString returns the index after the string literal whose content
+// starts at i. Quotes are escaped by doubling them and, in E'...' strings, with
+// a backslash.
+func skipString(query string, i int, backslash bool) int {
+	for i < len(query) {
+		switch {
+		case backslash && query[i] == '\\':
+			i += 2
+		case query[i] == '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i += 2
+
+				continue
+			}
+
+			return i + 1
+		default:
+			i++
+		}
+	}
+
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c >= 0x80
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "SELECT user_id, username\n\t FROM users   WHERE user_id = $1",
+			want:  "SELECT user_id, username FROM users WHERE user_id = $1",
+		},
+		{
+			query: "UPDATE users SET email = 'it''s@example.com', age = 42 WHERE id = 7",
+			want:  "UPDATE users SET email = ?, age = ? WHERE id = ?",
+		},
+		{
+			query: `SELECT E'a\'b', -1.5e-3, $tag$secret$tag$, $$x$$ FROM "Users2" t`,
+			want:  `SELECT ?, -?, ?, ? FROM "Users2" t`,
+		},
+		{
+			query: "-- leading comment\nSELECT /* inline */ col1 FROM t2 WHERE x IN (1, 2)",
+			want:  "SELECT col1 FROM t2 WHERE x IN (?, ?)",
+		},
+		{
+			query: "SELECT 'unterminated",
+			want:  "SELECT ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeSQL(tt.query))
+		})
+	}
+}
+
+func TestSQLOperation(t *testing.T) {
+	assert.Equal(t, "SELECT", sqlOperation("select 1"))
+	assert.Equal(t, "INSERT", sqlOperation("INSERT INTO t VALUES ($1)"))
+	assert.Equal(t, "SAVEPOINT", sqlOperation("SAVEPOINT sp_1"))
+	assert.Equal(t, "OTHER", sqlOperation("(SELECT 1)"))
+	assert.Equal(t, "OTHER", sqlOperation(""))
+}
+
+func TestDB_instrumentedStatements(t *testing.T) {
+	ctx := context.Background()
+	d, drv := newFakeDB(t)
+	d.obs = observer{pool: "primary", slowQuery: time.Nanosecond}
+
+	_, err := d.ExecContext(ctx, "DELETE FROM users WHERE user_id = $1", 1)
+	require.NoError(t, err)
+
+	rows, err := d.QueryContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	require.NoError(t, d.QueryRowContext(ctx, "SELECT 2").Err())
+
+	err = d.WithTx(ctx, nil, func(tx Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT 1")
+
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"DELETE FROM users WHERE user_id = $1", "SELECT 1", "SELECT 2", "BEGIN", "INSERT 1", "COMMIT",
+	}, drv.log())
+}
+
+func TestDB_instrumentedWithoutContext(t *testing.T) {
+	d, drv := newFakeDB(t)
+	d.obs = observer{pool: "primary"}
+
+	// Each statement has its own operation, so every one observed adds a
+	// series to the latency histogram.
+	before := testutil.CollectAndCount(queryDuration)
+
+	_, err := d.Exec("PLAINEXEC 1")
+	require.NoError(t, err)
+
+	rows, err := d.Query("PLAINQUERY 1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	require.NoError(t, d.QueryRow("PLAINROW 1").Err())
+
+	for _, query := range []string{"PREPEXEC 1", "PREPQUERY 1", "PREPROW 1"} {
+		stmt, err := d.Prepare(query)
+		require.NoError(t, err)
+		t.Cleanup(func() { stmt.Close() })
+
+		switch query {
+		case "PREPEXEC 1":
+			_, err = stmt.Exec()
+		case "PREPQUERY 1":
+			rows, err = stmt.Query()
+			if err == nil {
+				err = rows.Close()
+			}
+		default:
+			err = stmt.QueryRow().Err()
+		}
+
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, before+6, testutil.CollectAndCount(queryDuration))
+	assert.Equal(t, []string{
+		"PLAINEXEC 1", "PLAINQUERY 1", "PLAINROW 1", "PREPEXEC 1", "PREPQUERY 1", "PREPROW 1",
+	}, drv.log())
+}
+
+func TestObserver_errorStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	obs := observer{pool: "primary"}
+
+	for _, err := range []error{fmt.Errorf("lookup: %w", sql.ErrNoRows), errors.New("boom")} {
+		obs.observe(context.Background(), "SELECT 1", func(context.Context) error { return err })
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "a wrapped sql.ErrNoRows is not a failure")
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
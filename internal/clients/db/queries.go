@@ -1,3 +1,23 @@
 package db
 
-const GetAllQuery = "SELECT * FROM all"
+const (
+	InsertUserQuery = `INSERT INTO users (username, password, email) VALUES ($1, $2, $3) RETURNING user_id`
+
+	GetUserQuery = `SELECT user_id, username, password, email FROM users WHERE user_id = $1`
+
+	GetUserByEmailQuery = `SELECT user_id, username, password, email FROM users WHERE email = $1`
+
+	ListUsersQuery = `SELECT user_id, username, password, email FROM users WHERE user_id > $1 ORDER BY user_id LIMIT $2`
+
+	UpdateUserQuery = `UPDATE users SET username = $2, password = $3, email = $4 WHERE user_id = $1`
+
+	UpdateUserPasswordQuery = `UPDATE users SET password = $2 WHERE user_id = $1`
+
+	DeleteUserQuery = `DELETE FROM users WHERE user_id = $1`
+)
+
+// Names of the unique constraints created by 000001_create_users_table.
+const (
+	UsersUsernameKey = "users_username_key"
+	UsersEmailKey    = "users_email_key"
+)
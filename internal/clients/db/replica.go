@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-template/internal/config"
+	"go-template/pkg/logger"
+	"go-template/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Replica balancing strategies, set with DB_REPLICA_BALANCER.
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+var replicaHealthy = metrics.NewGaugeVec("db_replica_healthy", []string{"replica"},
+	"Whether a read replica answered its last health check (1) or not (0).")
+
+// replica is a read-only pool kept out of rotation while it fails its
+// health checks.
+type replica struct {
+	name    string
+	db      *pool
+	healthy atomic.Bool
+	// checked is set after the first health check; only checkReplicas uses it.
+	checked bool
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that reads made with it go to the primary. Use it
+// for the rest of a request after a write whose result must be visible, since
+// replicas may lag behind.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func primaryRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+
+	return v
+}
+
+// Reader returns the handle that read-only statements made with ctx should
+// run on: a healthy replica picked by the configured balancer, or the
+// primary when there are no healthy replicas or ctx was marked with
+// WithPrimary. Statements that write must not be run on the result.
+func (d *DB) Reader(ctx context.Context) Querier {
+	if r := d.pickReplica(ctx); r != nil {
+		return r.db
+	}
+
+	return d
+}
+
+// Reader returns q.Reader(ctx) when q is a *DB and q itself otherwise, so
+// reads made inside a transaction stay in it.
+func Reader(ctx context.Context, q Querier) Querier {
+	if d, ok := q.(*DB); ok {
+		return d.Reader(ctx)
+	}
+
+	return q
+}
+
+// pickReplica returns the replica for a read, or nil for the primary.
+func (d *DB) pickReplica(ctx context.Context) *replica {
+	if len(d.replicas) == 0 || primaryRequested(ctx) {
+		return nil
+	}
+
+	var picked *replica
+
+	switch d.balancer {
+	case LeastConnections:
+		inUse := 0
+
+		for _, r := range d.replicas {
+			if !r.healthy.Load() {
+				continue
+			}
+
+			if n := r.db.Stats().InUse; picked == nil || n < inUse {
+				picked, inUse = r, n
+			}
+		}
+	default:
+		start := int(d.next.Add(1) - 1)
+
+		for i := range d.replicas {
+			if r := d.replicas[(start+i)%len(d.replicas)]; r.healthy.Load() {
+				picked = r
+
+				break
+			}
+		}
+	}
+
+	return picked
+}
+
+// openReplicas creates a pool per entry of cfg.Replicas and checks them
+// once; unreachable replicas start out of rotation rather than failing Open.
+func (d *DB) openReplicas(ctx context.Context, cfg config.Database) error {
+	for i, entry := range cfg.Replicas {
+		sqlDB, err := sql.Open(driverName, replicaDSN(cfg, entry))
+		if err != nil {
+			d.closeReplicas()
+
+			return fmt.Errorf("failed to initialize replica %d: %w", i, err)
+		}
+
+		configurePool(sqlDB, cfg)
+
+		name := strconv.Itoa(i)
+		obs := observer{pool: "replica-" + name, slowQuery: cfg.SlowQueryThreshold}
+
+		d.replicas = append(d.replicas, &replica{name: name, db: &pool{DB: sqlDB, obs: obs}})
+	}
+
+	if len(d.replicas) == 0 {
+		return nil
+	}
+
+	d.balancer = cfg.ReplicaBalancer
+	d.checkReplicas(ctx)
+
+	d.wg.Add(1)
+
+	go d.watchReplicas(cfg.ReplicaCheckInterval)
+
+	return nil
+}
+
+// watchReplicas checks the replicas every interval until Close.
+func (d *DB) watchReplicas(interval time.Duration) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.checkReplicas(context.Background())
+		}
+	}
+}
+
+// checkReplicas pings every replica concurrently and takes the ones that
+// fail out of rotation until they answer again.
+func (d *DB) checkReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, r := range d.replicas {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err := r.db.PingContext(pingCtx)
+			cancel()
+
+			healthy := err == nil
+			was := r.healthy.Swap(healthy)
+
+			switch {
+			case healthy && !was:
+				logger.Info("Database replica is in rotation", zap.String("replica", r.name))
+			case !healthy && (was || !r.checked):
+				logger.Warn("Database replica is unhealthy, reading from the others",
+					zap.String("replica", r.name), zap.Error(err))
+			}
+
+			r.checked = true
+
+			if healthy {
+				replicaHealthy.WithLabelValues(r.name).Set(1)
+			} else {
+				replicaHealthy.WithLabelValues(r.name).Set(0)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (d *DB) closeReplicas() {
+	for _, r := range d.replicas {
+		r.db.Close()
+	}
+
+	d.replicas = nil
+}
+
+// replicaDSN returns the connection string for an entry of DB_REPLICAS. A
+// full DSN, either a postgres:// URL or key=value pairs, is used as is; a
+// bare host or host:port inherits every other setting from the primary.
+// IPv6 addresses are given bare or in brackets, with a port: [::1]:5432.
+func replicaDSN(cfg config.Database, entry string) string {
+	if strings.Contains(entry, "=") || strings.Contains(entry, "://") {
+		return entry
+	}
+
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		// No port: keep the primary's.
+		cfg.Address = strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]")
+
+		return DSN(cfg)
+	}
+
+	cfg.Address = host
+
+	if p, err := strconv.Atoi(port); err == nil {
+		cfg.Port = p
+	}
+
+	return DSN(cfg)
+}
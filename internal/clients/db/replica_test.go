@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-template/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReplicatedDB returns a DB backed by fake drivers with n healthy replicas.
+func newReplicatedDB(t *testing.T, n int, balancer string) (*DB, *fakeDriver, []*fakeDriver) {
+	t.Helper()
+
+	d, primary := newFakeDB(t)
+	d.balancer = balancer
+
+	drivers := make([]*fakeDriver, n)
+
+	for i := range n {
+		r, drv := newFakeDB(t)
+		drivers[i] = drv
+
+		d.replicas = append(d.replicas, &replica{name: string(rune('a' + i)), db: &pool{DB: r.DB}})
+		d.replicas[i].healthy.Store(true)
+	}
+
+	return d, primary, drivers
+}
+
+func TestDB_Reader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no replicas", func(t *testing.T) {
+		d, _ := newFakeDB(t)
+
+		assert.Same(t, d, d.Reader(ctx))
+	})
+
+	t.Run("round robin over healthy replicas", func(t *testing.T) {
+		d, _, _ := newReplicatedDB(t, 3, RoundRobin)
+		d.replicas[1].healthy.Store(false)
+
+		var got []Querier
+		for range 4 {
+			got = append(got, d.Reader(ctx))
+		}
+
+		assert.Equal(t, []Querier{d.replicas[0].db, d.replicas[2].db, d.replicas[2].db, d.replicas[0].db}, got)
+	})
+
+	t.Run("least connections", func(t *testing.T) {
+		d, _, _ := newReplicatedDB(t, 2, LeastConnections)
+
+		conn, err := d.replicas[0].db.Conn(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		assert.Same(t, d.replicas[1].db, d.Reader(ctx))
+	})
+
+	t.Run("primary when all replicas are unhealthy", func(t *testing.T) {
+		d, _, _ := newReplicatedDB(t, 2, RoundRobin)
+		d.replicas[0].healthy.Store(false)
+		d.replicas[1].healthy.Store(false)
+
+		assert.Same(t, d, d.Reader(ctx))
+	})
+
+	t.Run("primary requested", func(t *testing.T) {
+		d, _, _ := newReplicatedDB(t, 2, RoundRobin)
+
+		assert.Same(t, d, d.Reader(WithPrimary(ctx)))
+	})
+
+	t.Run("transactions read their own writes", func(t *testing.T) {
+		d, _, _ := newReplicatedDB(t, 1, RoundRobin)
+
+		err := d.WithTx(ctx, nil, func(tx Tx) error {
+			assert.Same(t, tx, Reader(ctx, tx))
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestDB_WithTx_readOnlyOnReplica(t *testing.T) {
+	ctx := context.Background()
+	d, primary, replicas := newReplicatedDB(t, 1, RoundRobin)
+	noop := func(Tx) error { return nil }
+
+	require.NoError(t, d.WithTx(ctx, &TxOptions{ReadOnly: true}, noop))
+	assert.Empty(t, primary.log())
+	assert.Equal(t, []string{"BEGIN READ ONLY", "COMMIT"}, replicas[0].log())
+
+	require.NoError(t, d.WithTx(WithPrimary(ctx), &TxOptions{ReadOnly: true}, noop))
+	assert.Equal(t, []string{"BEGIN READ ONLY", "COMMIT"}, primary.log())
+
+	require.NoError(t, d.WithTx(ctx, nil, noop))
+	assert.Equal(t, []string{"BEGIN", "COMMIT"}, primary.log())
+	assert.Empty(t, replicas[0].log())
+}
+
+func TestDB_checkReplicas(t *testing.T) {
+	d, _, replicas := newReplicatedDB(t, 2, RoundRobin)
+	replicas[1].fail = func(string) error { return errors.New("connection refused") }
+
+	d.checkReplicas(context.Background())
+	assert.True(t, d.replicas[0].healthy.Load())
+	assert.False(t, d.replicas[1].healthy.Load())
+
+	replicas[1].fail = nil
+
+	d.checkReplicas(context.Background())
+	assert.True(t, d.replicas[1].healthy.Load())
+}
+
+func TestReplicaDSN(t *testing.T) {
+	cfg := config.Database{Address: "primary", Port: 5432, Name: "app", User: "svc", SSLMode: "disable"}
+
+	assert.Equal(t,
+		`dbname='app' host='replica' port='5432' sslmode='disable' user='svc'`,
+		replicaDSN(cfg, "replica"))
+	assert.Equal(t,
+		`dbname='app' host='replica' port='6432' sslmode='disable' user='svc'`,
+		replicaDSN(cfg, "replica:6432"))
+	assert.Equal(t,
+		`dbname='app' host='::1' port='6432' sslmode='disable' user='svc'`,
+		replicaDSN(cfg, "[::1]:6432"))
+	assert.Equal(t,
+		`dbname='app' host='::1' port='5432' sslmode='disable' user='svc'`,
+		replicaDSN(cfg, "[::1]"))
+	assert.Equal(t,
+		`dbname='app' host='fe80::1' port='5432' sslmode='disable' user='svc'`,
+		replicaDSN(cfg, "fe80::1"))
+	assert.Equal(t, "postgres://ro@replica/app", replicaDSN(cfg, "postgres://ro@replica/app"))
+	assert.Equal(t, "host=replica user=ro", replicaDSN(cfg, "host=replica user=ro"))
+}
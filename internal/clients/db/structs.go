@@ -1,5 +1,9 @@
 package db
 
-type SampleStruct struct {
-	Name string `json:"name"`
+// User is a row of the users table.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"-"`
+	Email    string `json:"email"`
 }
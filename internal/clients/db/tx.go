@@ -0,0 +1,235 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go-template/pkg/metrics"
+	"go-template/pkg/tracer"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultTxRetries is how often WithTx retries a transaction that failed
+	// with a serialization failure or deadlock when TxOptions.MaxRetries is 0.
+	DefaultTxRetries = 3
+
+	txRetryBaseDelay = 20 * time.Millisecond
+	txRetryMaxDelay  = 500 * time.Millisecond
+)
+
+// SQLSTATE codes after which a transaction can safely be retried.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+var (
+	txDuration = metrics.NewHistogramVec("db_transaction_duration_seconds", []string{"result"},
+		"Duration of database transactions including retries, by result (commit, rollback).")
+	txRetries = metrics.NewCounterVec("db_transaction_retries_total", []string{"sqlstate"},
+		"Database transactions retried after a serialization failure or deadlock.")
+)
+
+// Querier runs statements. It is implemented by *sql.DB, *sql.Tx, *DB and Tx.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is the transaction handed to WithTx callbacks.
+type Tx interface {
+	Querier
+
+	// WithTx runs fn in a nested transaction backed by a savepoint. An error
+	// or panic in fn rolls back to the savepoint only; the error is still
+	// returned so the caller decides whether the outer transaction fails.
+	WithTx(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// TxOptions configures a transaction started by WithTx.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries bounds the retries after serialization failures and
+	// deadlocks. Zero means DefaultTxRetries, a negative value disables them.
+	MaxRetries int
+}
+
+// WithTx runs fn in a transaction. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics; panics
+// are re-raised after the rollback. When fn or the commit fails with a
+// serialization failure (40001) or deadlock (40P01), the whole transaction
+// is retried with a jittered exponential backoff, so fn must be safe to run
+// more than once. opts may be nil.
+//
+// Read-only transactions run on a replica, as picked by Reader, unless ctx
+// was marked with WithPrimary.
+func (d *DB) WithTx(ctx context.Context, opts *TxOptions, fn func(tx Tx) error) (err error) {
+	o := TxOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	maxRetries := o.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultTxRetries
+	}
+
+	ctx, span := tracer.StartSpan(ctx, "db.transaction",
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.transaction.isolation", o.Isolation.String()),
+		attribute.Bool("db.transaction.read_only", o.ReadOnly),
+	)
+	defer span.End()
+
+	start := time.Now()
+	result := "rollback"
+
+	defer func() {
+		txDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}()
+
+	delay := txRetryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		sqlDB, obs := d.DB, d.obs
+		if o.ReadOnly {
+			if r := d.pickReplica(ctx); r != nil {
+				sqlDB, obs = r.db.DB, r.db.obs
+			}
+		}
+
+		err = runTx(ctx, sqlDB, obs, o, fn)
+		if err == nil {
+			result = "commit"
+			span.SetAttributes(attribute.Int("db.transaction.attempts", attempt))
+
+			return nil
+		}
+
+		code, retryable := retryableTxError(err)
+		if !retryable || attempt > maxRetries {
+			span.SetAttributes(attribute.Int("db.transaction.attempts", attempt))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			return err
+		}
+
+		txRetries.WithLabelValues(code).Inc()
+		span.AddEvent("retry", oteltrace.WithAttributes(
+			attribute.Int("db.transaction.attempt", attempt),
+			attribute.String("db.sqlstate", code),
+		))
+
+		// Full jitter keeps competing transactions from retrying in lockstep.
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(rand.N(delay) + time.Millisecond):
+		}
+
+		delay = min(delay*2, txRetryMaxDelay)
+	}
+}
+
+// runTx runs a single attempt of a transaction on sqlDB.
+func runTx(ctx context.Context, sqlDB *sql.DB, obs observer, o TxOptions, fn func(tx Tx) error) error {
+	sqlTx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+
+			panic(p)
+		}
+	}()
+
+	if err := fn(&tx{Tx: sqlTx, obs: obs}); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+		}
+
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// tx implements Tx on top of *sql.Tx, tracking the savepoint depth.
+type tx struct {
+	*sql.Tx
+	obs   observer
+	depth int
+}
+
+func (t *tx) WithTx(ctx context.Context, fn func(tx Tx) error) error {
+	name := fmt.Sprintf("sp_%d", t.depth+1)
+
+	ctx, span := tracer.StartSpan(ctx, "db.savepoint", attribute.String("db.savepoint", name))
+	defer span.End()
+
+	if _, err := t.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		span.RecordError(err)
+
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = t.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+
+			panic(p)
+		}
+	}()
+
+	if err := fn(&tx{Tx: t.Tx, obs: t.obs, depth: t.depth + 1}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		if _, rbErr := t.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back to savepoint: %w", rbErr))
+		}
+
+		return err
+	}
+
+	if _, err := t.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// retryableTxError reports whether err is a serialization failure or
+// deadlock, along with its SQLSTATE.
+func retryableTxError(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+
+	switch code := string(pqErr.Code); code {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return code, true
+	}
+
+	return "", false
+}
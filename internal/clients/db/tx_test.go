@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_WithTx(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		opts    *TxOptions
+		fail    func(calls *int) func(stmt string) error
+		fn      func(ctx context.Context) func(tx Tx) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "commit on success",
+			fn: func(ctx context.Context) func(tx Tx) error {
+				return func(tx Tx) error {
+					_, err := tx.ExecContext(ctx, "INSERT 1")
+
+					return err
+				}
+			},
+			want: []string{"BEGIN", "INSERT 1", "COMMIT"},
+		},
+		{
+			name: "rollback on error",
+			fn: func(context.Context) func(tx Tx) error {
+				return func(Tx) error { return errBoom }
+			},
+			wantErr: errBoom,
+			want:    []string{"BEGIN", "ROLLBACK"},
+		},
+		{
+			name: "isolation and read only",
+			opts: &TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true},
+			fn: func(context.Context) func(tx Tx) error {
+				return func(Tx) error { return nil }
+			},
+			want: []string{"BEGIN ISOLATION LEVEL Serializable READ ONLY", "COMMIT"},
+		},
+		{
+			name: "retry on serialization failure at commit",
+			fail: func(calls *int) func(stmt string) error {
+				return func(stmt string) error {
+					if stmt == "COMMIT" {
+						*calls++
+						if *calls == 1 {
+							return &pq.Error{Code: "40001"}
+						}
+					}
+
+					return nil
+				}
+			},
+			fn: func(ctx context.Context) func(tx Tx) error {
+				return func(tx Tx) error {
+					_, err := tx.ExecContext(ctx, "UPDATE 1")
+
+					return err
+				}
+			},
+			want: []string{"BEGIN", "UPDATE 1", "COMMIT", "BEGIN", "UPDATE 1", "COMMIT"},
+		},
+		{
+			name: "retries are bounded",
+			opts: &TxOptions{MaxRetries: 1},
+			fail: func(*int) func(stmt string) error {
+				return func(stmt string) error {
+					if stmt == "UPDATE 1" {
+						return &pq.Error{Code: "40P01"}
+					}
+
+					return nil
+				}
+			},
+			fn: func(ctx context.Context) func(tx Tx) error {
+				return func(tx Tx) error {
+					_, err := tx.ExecContext(ctx, "UPDATE 1")
+
+					return err
+				}
+			},
+			wantErr: &pq.Error{Code: "40P01"},
+			want:    []string{"BEGIN", "UPDATE 1", "ROLLBACK", "BEGIN", "UPDATE 1", "ROLLBACK"},
+		},
+		{
+			name: "nested transaction rolls back to savepoint",
+			fn: func(ctx context.Context) func(tx Tx) error {
+				return func(tx Tx) error {
+					if _, err := tx.ExecContext(ctx, "INSERT 1"); err != nil {
+						return err
+					}
+
+					nestedErr := tx.WithTx(ctx, func(tx Tx) error {
+						if err := tx.WithTx(ctx, func(Tx) error { return nil }); err != nil {
+							return err
+						}
+
+						return errBoom
+					})
+					if !errors.Is(nestedErr, errBoom) {
+						return errors.New("nested error not returned")
+					}
+
+					return nil
+				}
+			},
+			want: []string{
+				"BEGIN", "INSERT 1",
+				"SAVEPOINT sp_1", "SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_1",
+				"COMMIT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			database, driver := newFakeDB(t)
+
+			var calls int
+			if tt.fail != nil {
+				driver.fail = tt.fail(&calls)
+			}
+
+			err := database.WithTx(ctx, tt.opts, tt.fn(ctx))
+
+			if tt.wantErr != nil {
+				var pqErr *pq.Error
+				if errors.As(tt.wantErr, &pqErr) {
+					var got *pq.Error
+					require.ErrorAs(t, err, &got)
+					assert.Equal(t, pqErr.Code, got.Code)
+				} else {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.want, driver.log())
+		})
+	}
+}
+
+func TestDB_WithTx_panic(t *testing.T) {
+	ctx := context.Background()
+	database, driver := newFakeDB(t)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = database.WithTx(ctx, nil, func(tx Tx) error {
+			return tx.WithTx(ctx, func(Tx) error { panic("boom") })
+		})
+	})
+
+	assert.Equal(t, []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}, driver.log())
+}
@@ -0,0 +1,302 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-template/pkg/logger"
+	"go-template/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned, wrapped, for requests refused because the
+// circuit breaker of their host is open. Check for it with errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var breakerState = metrics.NewGaugeVec("http_client_circuit_breaker_state", []string{"host"},
+	"State of the circuit breaker per upstream host: 0 closed, 1 half-open, 2 open.")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a few probe requests through to test recovery.
+	BreakerHalfOpen
+	// BreakerOpen refuses requests with ErrCircuitOpen.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerOptions configures the circuit breaker kept for every upstream
+// host. A request fails when it gets a transport error or a 5xx response.
+// The zero value disables the breaker.
+type BreakerOptions struct {
+	// ConsecutiveFailures opens the breaker after this many failures in a
+	// row. Zero disables the rule.
+	ConsecutiveFailures int
+	// FailureRate opens the breaker when this fraction of the requests in
+	// the current Window failed, once at least MinRequests were made. Zero
+	// disables the rule.
+	FailureRate float64
+	MinRequests int
+	Window      time.Duration
+	// OpenTimeout is how long the breaker stays open before turning
+	// half-open.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of requests let through while half-open;
+	// the breaker closes when all of them succeed and opens again as soon as
+	// one fails.
+	HalfOpenProbes int
+}
+
+// DefaultBreakerOptions opens after 5 consecutive failures, or when half of
+// at least 20 requests in a minute fail, and probes again after 30s.
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		ConsecutiveFailures: 5,
+		FailureRate:         0.5,
+		MinRequests:         20,
+		Window:              time.Minute,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenProbes:      1,
+	}
+}
+
+func (o BreakerOptions) enabled() bool {
+	return o.ConsecutiveFailures > 0 || o.FailureRate > 0
+}
+
+// outcome is how a request counts toward its breaker.
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	// ignored requests, such as those canceled by the caller, say nothing
+	// about the upstream.
+	ignored
+)
+
+func outcomeOf(resp *http.Response, err error) outcome {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ignored
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError:
+		return failure
+	}
+
+	return success
+}
+
+// breakers holds one breaker per host.
+type breakers struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	byHost map[string]*breaker
+}
+
+func newBreakers(opts BreakerOptions) *breakers {
+	if !opts.enabled() {
+		return nil
+	}
+
+	return &breakers{opts: opts, now: time.Now, byHost: make(map[string]*breaker)}
+}
+
+// allow asks the breaker of host for permission to send a request. When it
+// is granted, done must be called with the request's outcome.
+func (bs *breakers) allow(host string) (done func(outcome), err error) {
+	if bs == nil {
+		return func(outcome) {}, nil
+	}
+
+	bs.mu.Lock()
+	b, ok := bs.byHost[host]
+
+	if !ok {
+		b = &breaker{host: host, opts: bs.opts, now: bs.now}
+		b.windowStart = b.now()
+		bs.byHost[host] = b
+		breakerState.WithLabelValues(host).Set(float64(BreakerClosed))
+	}
+	bs.mu.Unlock()
+
+	return b.allow()
+}
+
+// state returns the state of the breaker of host.
+func (bs *breakers) state(host string) BreakerState {
+	if bs == nil {
+		return BreakerClosed
+	}
+
+	bs.mu.Lock()
+	b, ok := bs.byHost[host]
+	bs.mu.Unlock()
+
+	if !ok {
+		return BreakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+
+	return b.state
+}
+
+type breaker struct {
+	host string
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu    sync.Mutex
+	state BreakerState
+	// generation changes with every state change, so that requests started
+	// in an earlier state are not counted.
+	generation uint64
+	// Closed state counters.
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	// Open and half-open state.
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+func (b *breaker) allow() (func(outcome), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+
+	switch b.state {
+	case BreakerOpen:
+		retryIn := b.opts.OpenTimeout - b.now().Sub(b.openedAt)
+
+		return nil, fmt.Errorf("%w for %s, retry in %s", ErrCircuitOpen, b.host, retryIn.Round(time.Millisecond))
+	case BreakerHalfOpen:
+		if b.probes >= max(b.opts.HalfOpenProbes, 1) {
+			return nil, fmt.Errorf("%w for %s, probing recovery", ErrCircuitOpen, b.host)
+		}
+
+		b.probes++
+	}
+
+	generation := b.generation
+
+	return func(o outcome) { b.record(generation, o) }, nil
+}
+
+// refresh moves an open breaker whose timeout passed to half-open and
+// starts a new window for a closed one. It must be called with mu held.
+func (b *breaker) refresh() {
+	now := b.now()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.opts.OpenTimeout {
+			b.setState(BreakerHalfOpen)
+		}
+	case BreakerClosed:
+		if b.opts.Window > 0 && now.Sub(b.windowStart) >= b.opts.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+}
+
+// record counts the outcome of a request allowed in the given generation.
+func (b *breaker) record(generation uint64, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		// The breaker moved on while the request was in flight.
+		return
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probes--
+
+		switch o {
+		case failure:
+			b.setState(BreakerOpen)
+		case success:
+			b.successes++
+			if b.successes >= max(b.opts.HalfOpenProbes, 1) {
+				b.setState(BreakerClosed)
+			}
+		}
+	case BreakerClosed:
+		if o == ignored {
+			return
+		}
+
+		b.refresh()
+		b.requests++
+
+		if o == success {
+			b.consecutive = 0
+
+			return
+		}
+
+		b.failures++
+		b.consecutive++
+
+		if b.opts.ConsecutiveFailures > 0 && b.consecutive >= b.opts.ConsecutiveFailures ||
+			b.opts.FailureRate > 0 && b.requests >= b.opts.MinRequests &&
+				float64(b.failures)/float64(b.requests) >= b.opts.FailureRate {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// setState switches to state and resets the counters. It must be called
+// with mu held.
+func (b *breaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.requests, b.failures, b.consecutive = 0, 0, 0
+	b.probes, b.successes = 0, 0
+	b.windowStart = b.now()
+
+	if state == BreakerOpen {
+		b.openedAt = b.now()
+	}
+
+	breakerState.WithLabelValues(b.host).Set(float64(state))
+
+	fields := []zap.Field{zap.String("host", b.host), zap.Stringer("from", from), zap.Stringer("to", state)}
+	if state == BreakerOpen {
+		logger.Warn("Circuit breaker opened, failing requests fast", append(fields, zap.Duration("open_timeout", b.opts.OpenTimeout))...)
+	} else {
+		logger.Info("Circuit breaker state changed", fields...)
+	}
+}
@@ -0,0 +1,152 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable time source for breakers.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestBreakers(opts BreakerOptions) (*breakers, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bs := newBreakers(opts)
+	bs.now = clock.now
+
+	return bs, clock
+}
+
+func request(t *testing.T, bs *breakers, o outcome) error {
+	t.Helper()
+
+	done, err := bs.allow("upstream")
+	if err != nil {
+		return err
+	}
+
+	done(o)
+
+	return nil
+}
+
+func TestBreaker_consecutiveFailures(t *testing.T) {
+	bs, clock := newTestBreakers(BreakerOptions{ConsecutiveFailures: 3, OpenTimeout: 10 * time.Second, HalfOpenProbes: 2})
+
+	require.NoError(t, request(t, bs, failure))
+	require.NoError(t, request(t, bs, failure))
+	require.NoError(t, request(t, bs, success))
+	require.NoError(t, request(t, bs, failure))
+	require.NoError(t, request(t, bs, ignored))
+	require.NoError(t, request(t, bs, failure))
+	assert.Equal(t, BreakerClosed, bs.state("upstream"))
+
+	require.NoError(t, request(t, bs, failure))
+	assert.Equal(t, BreakerOpen, bs.state("upstream"))
+
+	err := request(t, bs, success)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Contains(t, err.Error(), "upstream, retry in 10s")
+
+	clock.t = clock.t.Add(10 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, bs.state("upstream"))
+
+	// Two probes are let through, a third request is refused meanwhile.
+	done1, err := bs.allow("upstream")
+	require.NoError(t, err)
+	done2, err := bs.allow("upstream")
+	require.NoError(t, err)
+	assert.ErrorIs(t, request(t, bs, success), ErrCircuitOpen)
+
+	done1(success)
+	assert.Equal(t, BreakerHalfOpen, bs.state("upstream"))
+	done2(success)
+	assert.Equal(t, BreakerClosed, bs.state("upstream"))
+}
+
+func TestBreaker_halfOpenFailureReopens(t *testing.T) {
+	bs, clock := newTestBreakers(BreakerOptions{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	// A request started while closed finishes after the breaker opened.
+	stale, err := bs.allow("upstream")
+	require.NoError(t, err)
+	require.NoError(t, request(t, bs, failure))
+
+	clock.t = clock.t.Add(time.Second)
+	stale(failure)
+	assert.Equal(t, BreakerHalfOpen, bs.state("upstream"))
+
+	require.NoError(t, request(t, bs, failure))
+	assert.Equal(t, BreakerOpen, bs.state("upstream"))
+}
+
+func TestBreaker_failureRate(t *testing.T) {
+	bs, clock := newTestBreakers(BreakerOptions{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, OpenTimeout: time.Second})
+
+	require.NoError(t, request(t, bs, failure))
+	require.NoError(t, request(t, bs, success))
+	require.NoError(t, request(t, bs, success))
+
+	// A new window forgets the earlier requests.
+	clock.t = clock.t.Add(time.Minute)
+	require.NoError(t, request(t, bs, failure))
+	require.NoError(t, request(t, bs, success))
+	require.NoError(t, request(t, bs, success))
+	assert.Equal(t, BreakerClosed, bs.state("upstream"))
+
+	require.NoError(t, request(t, bs, failure))
+	assert.Equal(t, BreakerOpen, bs.state("upstream"))
+}
+
+func TestBreaker_disabled(t *testing.T) {
+	assert.Nil(t, newBreakers(BreakerOptions{}))
+
+	var bs *breakers
+
+	for range 10 {
+		require.NoError(t, request(t, bs, failure))
+	}
+}
+
+func TestClient_Do_breaker(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client, err := NewClient(ClientOptions{
+		BaseURL:     serverURL,
+		RetryPolicy: RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		Breaker:     BreakerOptions{ConsecutiveFailures: 2, OpenTimeout: time.Minute},
+	})
+	require.NoError(t, err)
+
+	_, err = client.Do(context.Background(), http.MethodGet, "/test", nil, nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls.Load(), "retries stop once the breaker opens")
+
+	_, err = client.Do(context.Background(), http.MethodGet, "/test", nil, nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,301 @@
+package httpClient
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-template/pkg/logger"
+	"go-template/pkg/metrics"
+	"go-template/pkg/tracer"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+var cacheRequests = metrics.NewCounterVec("http_client_cache_requests_total", []string{"host", "result"},
+	"Cacheable outbound HTTP requests by upstream host and result: hit, stale, revalidated or miss.")
+
+// Cache stores the responses of GET requests made with Client.Do, keyed by
+// URL. Implementations must be safe for concurrent use and must not modify
+// the entries they are given or return.
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// CachedResponse is a 200 response held in a Cache.
+type CachedResponse struct {
+	// Header holds the response headers, including the ETag and
+	// Last-Modified validators used to revalidate it.
+	Header http.Header
+	Body   []byte
+	// Expires is when the response stops being fresh.
+	Expires time.Time
+	// StaleUntil ends the stale-while-revalidate window, during which the
+	// stale response is served while it is revalidated in the background.
+	StaleUntil time.Time
+}
+
+func (r *CachedResponse) hasValidator() bool {
+	return r.Header.Get("ETag") != "" || r.Header.Get("Last-Modified") != ""
+}
+
+// newCachedResponse returns the cache entry for a response received at
+// now, or nil when it must not be stored. Without max-age, a response is
+// stored only when it has a validator, and is revalidated on every use.
+// Private responses are not stored, nor are those varying with request
+// headers, since entries are keyed by URL alone.
+func newCachedResponse(header http.Header, body []byte, now time.Time) *CachedResponse {
+	cc := parseCacheControl(header)
+	if _, ok := cc["no-store"]; ok {
+		return nil
+	}
+
+	if _, ok := cc["private"]; ok || header.Get("Vary") != "" {
+		return nil
+	}
+
+	var maxAge time.Duration
+	if _, noCache := cc["no-cache"]; !noCache {
+		maxAge = seconds(cc["max-age"]) - seconds(header.Get("Age"))
+	}
+
+	r := &CachedResponse{Header: header, Body: body, Expires: now.Add(maxAge)}
+	r.StaleUntil = r.Expires.Add(seconds(cc["stale-while-revalidate"]))
+
+	if maxAge <= 0 && !r.hasValidator() {
+		return nil
+	}
+
+	return r
+}
+
+// parseCacheControl returns the directives of the Cache-Control header,
+// lowercased, with their unquoted arguments.
+func parseCacheControl(header http.Header) map[string]string {
+	cc := make(map[string]string)
+
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+
+	return cc
+}
+
+// seconds parses a delta-seconds value, treating invalid ones as 0.
+func seconds(v string) time.Duration {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// responseCache serves GET requests from a Cache.
+type responseCache struct {
+	store Cache
+	now   func() time.Time
+
+	// revalidating holds the keys being revalidated in the background.
+	revalidating sync.Map
+}
+
+func newResponseCache(store Cache) *responseCache {
+	if store == nil {
+		return nil
+	}
+
+	return &responseCache{store: store, now: time.Now}
+}
+
+// do returns the body for req from the cache while it is fresh and
+// otherwise fetches or revalidates it, updating the cache.
+func (c *responseCache) do(ctx context.Context, client Client, req *http.Request) ([]byte, error) {
+	key := req.URL.String()
+
+	entry, _ := c.store.Get(key)
+
+	now := c.now()
+
+	switch {
+	case entry == nil:
+	case now.Before(entry.Expires):
+		c.record(ctx, req, "hit")
+
+		return bytes.Clone(entry.Body), nil
+	case now.Before(entry.StaleUntil):
+		c.record(ctx, req, "stale")
+
+		if _, running := c.revalidating.LoadOrStore(key, struct{}{}); !running {
+			go c.revalidate(context.WithoutCancel(ctx), client, req, key, entry)
+		}
+
+		return bytes.Clone(entry.Body), nil
+	case !entry.hasValidator():
+		entry = nil
+	}
+
+	body, revalidated, err := c.fetch(ctx, client, req, key, entry)
+
+	if revalidated {
+		c.record(ctx, req, "revalidated")
+	} else {
+		c.record(ctx, req, "miss")
+	}
+
+	return body, err
+}
+
+// revalidate refreshes a stale entry served meanwhile.
+func (c *responseCache) revalidate(ctx context.Context, client Client, req *http.Request, key string, entry *CachedResponse) {
+	defer c.revalidating.Delete(key)
+
+	ctx, cancel := context.WithTimeout(ctx, client.timeouts(ctx).Call)
+	defer cancel()
+
+	ctx, span := tracer.StartSpan(ctx, "HTTP cache revalidation", attribute.String("http.url", req.URL.Redacted()))
+	defer span.End()
+
+	if _, _, err := c.fetch(ctx, client, req.Clone(ctx), key, entry); err != nil {
+		span.RecordError(err)
+		logger.Warn("Failed to revalidate cached response", zap.String("url", req.URL.Redacted()), zap.Error(err))
+	}
+}
+
+// fetch sends req, conditionally when there is an entry to revalidate, and
+// stores the response. It reports whether the entry was still valid.
+func (c *responseCache) fetch(ctx context.Context, client Client, req *http.Request, key string, entry *CachedResponse) ([]byte, bool, error) {
+	if entry != nil {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, body, err := client.roundTrip(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	header := resp.Header
+	revalidated := resp.StatusCode == http.StatusNotModified
+
+	if revalidated {
+		// The 304 updates the stored headers, and so the freshness.
+		header = entry.Header.Clone()
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+
+		body = bytes.Clone(entry.Body)
+	} else if resp.StatusCode != http.StatusOK {
+		return body, false, nil
+	}
+
+	if updated := newCachedResponse(header, bytes.Clone(body), c.now()); updated != nil {
+		c.store.Set(key, updated)
+	} else {
+		c.store.Delete(key)
+	}
+
+	return body, revalidated, nil
+}
+
+func (c *responseCache) record(ctx context.Context, req *http.Request, result string) {
+	cacheRequests.WithLabelValues(req.URL.Host, result).Inc()
+	tracer.SpanFromContext(ctx).SetAttributes(attribute.String("http.cache", result))
+}
+
+// LRUCache is an in-memory Cache holding a bounded number of responses and
+// evicting the least recently used.
+type LRUCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+// NewLRUCache returns an empty LRUCache for up to maxEntries responses.
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		maxEntries: max(maxEntries, 1),
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*lruEntry).resp, true
+}
+
+func (c *LRUCache) Set(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(e)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, resp: resp})
+
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of responses held.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
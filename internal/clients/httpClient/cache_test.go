@@ -0,0 +1,202 @@
+package httpClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCachedResponse(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		stored     bool
+		expires    time.Duration
+		staleUntil time.Duration
+	}{
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=60"}}, true, time.Minute, time.Minute},
+		{"age subtracted", http.Header{"Cache-Control": {"max-age=60"}, "Age": {"20"}}, true, 40 * time.Second, 40 * time.Second},
+		{"stale-while-revalidate", http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=30"}}, true, time.Minute, 90 * time.Second},
+		{"no-store", http.Header{"Cache-Control": {"no-store, max-age=60"}}, false, 0, 0},
+		{"private", http.Header{"Cache-Control": {"private, max-age=60"}}, false, 0, 0},
+		{"vary", http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept-Encoding"}}, false, 0, 0},
+		{"no-cache with validator", http.Header{"Cache-Control": {"no-cache, max-age=60"}, "Etag": {`"v1"`}}, true, 0, 0},
+		{"validator only", http.Header{"Last-Modified": {"Wed, 01 Jan 2025 00:00:00 GMT"}}, true, 0, 0},
+		{"neither max-age nor validator", http.Header{"Cache-Control": {"Max-Age=abc"}}, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCachedResponse(tt.header, []byte("body"), now)
+			if !tt.stored {
+				assert.Nil(t, r)
+
+				return
+			}
+
+			require.NotNil(t, r)
+			assert.Equal(t, now.Add(tt.expires), r.Expires)
+			assert.Equal(t, now.Add(tt.staleUntil), r.StaleUntil)
+		})
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", &CachedResponse{Body: []byte("a")})
+	c.Set("b", &CachedResponse{Body: []byte("b")})
+
+	_, ok := c.Get("a") // b is now the least recently used
+	require.True(t, ok)
+
+	c.Set("c", &CachedResponse{Body: []byte("c")})
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Set("a", &CachedResponse{Body: []byte("a2")})
+
+	a, ok := c.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a2", string(a.Body))
+
+	c.Delete("a")
+
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestClient_Do_cache(t *testing.T) {
+	var requests, notModified atomic.Int32
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Authorization")
+		case "/swr":
+			w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+		}
+
+		_, _ = fmt.Fprintf(w, `{"n":%d}`, n)
+	}, ClientOptions{Cache: NewLRUCache(10)})
+
+	clock := &fakeClock{t: time.Now()}
+	client.cache.now = clock.now
+
+	get := func(path string) string {
+		t.Helper()
+
+		body, err := client.Do(context.Background(), http.MethodGet, path, nil, nil)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	t.Run("fresh responses are served from the cache", func(t *testing.T) {
+		requests.Store(0)
+
+		assert.Equal(t, `{"n":1}`, get("/fresh"))
+		assert.Equal(t, `{"n":1}`, get("/fresh"))
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("query arguments are part of the key", func(t *testing.T) {
+		requests.Store(0)
+
+		_, err := client.Do(context.Background(), http.MethodGet, "/fresh", nil, map[string]string{"page": "2"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("revalidated with the ETag", func(t *testing.T) {
+		requests.Store(0)
+
+		assert.Equal(t, `{"n":1}`, get("/etag"))
+		assert.Equal(t, `{"n":1}`, get("/etag"))
+		assert.Equal(t, int32(2), requests.Load())
+		assert.Equal(t, int32(1), notModified.Load())
+	})
+
+	t.Run("no-store is not cached", func(t *testing.T) {
+		requests.Store(0)
+
+		assert.Equal(t, `{"n":1}`, get("/no-store"))
+		assert.Equal(t, `{"n":2}`, get("/no-store"))
+	})
+
+	t.Run("private and varying responses are not cached", func(t *testing.T) {
+		requests.Store(0)
+
+		assert.Equal(t, `{"n":1}`, get("/private"))
+		assert.Equal(t, `{"n":2}`, get("/private"))
+		assert.Equal(t, `{"n":3}`, get("/vary"))
+		assert.Equal(t, `{"n":4}`, get("/vary"))
+	})
+
+	t.Run("stale while revalidating", func(t *testing.T) {
+		requests.Store(0)
+
+		assert.Equal(t, `{"n":1}`, get("/swr"))
+
+		clock.t = clock.t.Add(2 * time.Second)
+
+		assert.Equal(t, `{"n":1}`, get("/swr"), "the stale response is served")
+		require.Eventually(t, func() bool {
+			return get("/swr") == `{"n":2}`
+		}, time.Second, 10*time.Millisecond, "the background revalidation updates the cache")
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("only GET is cached", func(t *testing.T) {
+		requests.Store(0)
+
+		_, err := client.Do(context.Background(), http.MethodPost, "/fresh", nil, nil)
+		require.NoError(t, err)
+		_, err = client.Do(context.Background(), http.MethodPost, "/fresh", nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+}
+
+func TestClient_Do_cacheStatusError(t *testing.T) {
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusNotFound)
+	}, ClientOptions{Cache: NewLRUCache(10)})
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/missing", nil, nil)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+}
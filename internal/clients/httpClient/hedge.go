@@ -0,0 +1,191 @@
+package httpClient
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go-template/pkg/metrics"
+	"go-template/pkg/tracer"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// hedgeSamples is the number of recent latencies kept per host.
+	hedgeSamples = 100
+	// minHedgeSamples is the number of latencies needed before hedging.
+	minHedgeSamples = 20
+)
+
+var hedgeCounter = metrics.NewCounterVec("http_client_hedges_total", []string{"host", "result"},
+	"Hedged outbound HTTP requests by upstream host and whether the hedge answered first: won or lost, or failed when both attempts did.")
+
+// HedgeOptions configures request hedging: when the first attempt of an
+// idempotent request has not answered after the 95th percentile of the
+// recent latencies of its host, a second one is sent, the first response
+// is used and the other request canceled. The zero value disables hedging.
+type HedgeOptions struct {
+	Enabled bool
+	// MinDelay is the least wait before hedging, so that a fast upstream is
+	// not called twice over small variations in latency.
+	MinDelay time.Duration
+}
+
+// hedging tracks the latencies of each host.
+type hedging struct {
+	minDelay time.Duration
+
+	mu     sync.Mutex
+	byHost map[string]*latencies
+}
+
+// latencies is a ring of the recent latencies of a host.
+type latencies struct {
+	samples [hedgeSamples]time.Duration
+	n       int
+}
+
+func newHedging(opts HedgeOptions) *hedging {
+	if !opts.Enabled {
+		return nil
+	}
+
+	return &hedging{minDelay: opts.MinDelay, byHost: make(map[string]*latencies)}
+}
+
+// observe records the latency of a successful attempt.
+func (h *hedging) observe(host string, d time.Duration) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.byHost[host]
+	if !ok {
+		l = &latencies{}
+		h.byHost[host] = l
+	}
+
+	l.samples[l.n%hedgeSamples] = d
+	l.n++
+}
+
+// delay returns the wait before hedging req. It reports false when req must
+// not be hedged: it is not idempotent, or too few latencies of its host are
+// known.
+func (h *hedging) delay(req *http.Request) (time.Duration, bool) {
+	if h == nil || !idempotent(req.Method) {
+		return 0, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.byHost[req.URL.Host]
+	if !ok || l.n < minHedgeSamples {
+		return 0, false
+	}
+
+	samples := slices.Clone(l.samples[:min(l.n, hedgeSamples)])
+	slices.Sort(samples)
+
+	p95 := samples[(len(samples)*95+99)/100-1]
+
+	return max(p95, h.minDelay), true
+}
+
+// hedge makes attempt n of req and, when it has not answered within the
+// hedging delay, a second one, returning whichever response comes first.
+// An error is only returned once both attempts failed.
+func (client Client) hedge(ctx context.Context, req *http.Request, n int) (*http.Response, error) {
+	delay, ok := client.hedging.delay(req)
+	if !ok {
+		return client.attempt(ctx, req, n)
+	}
+
+	type result struct {
+		resp  *http.Response
+		err   error
+		hedge bool
+	}
+
+	results := make(chan result, 2)
+	cancels := make(map[bool]context.CancelFunc, 2)
+
+	start := func(hedge bool) {
+		actx, cancel := context.WithCancel(ctx)
+		cancels[hedge] = cancel
+
+		go func() {
+			resp, err := client.attempt(actx, req, n)
+			results <- result{resp, err, hedge}
+		}()
+	}
+
+	start(false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending := 1
+
+	for {
+		select {
+		case <-timer.C:
+			tracer.SpanFromContext(ctx).AddEvent("hedge", oteltrace.WithAttributes(
+				attribute.Int("http.attempt", n),
+				attribute.String("http.hedge_delay", delay.String()),
+			))
+			start(true)
+
+			pending++
+		case r := <-results:
+			pending--
+
+			if r.err != nil && pending > 0 {
+				cancels[r.hedge]()
+
+				continue
+			}
+
+			if other, hedged := cancels[!r.hedge]; hedged {
+				outcome := "failed"
+				if r.err == nil {
+					outcome = "lost"
+					if r.hedge {
+						outcome = "won"
+					}
+				}
+
+				hedgeCounter.WithLabelValues(req.URL.Host, outcome).Inc()
+
+				// Cancel the other request and discard its response, if any.
+				other()
+			}
+
+			if pending > 0 {
+				go func() {
+					if other := <-results; other.resp != nil {
+						other.resp.Body.Close()
+					}
+				}()
+			}
+
+			if r.resp == nil {
+				cancels[r.hedge]()
+
+				return nil, r.err
+			}
+
+			r.resp.Body = &releaseBody{ReadCloser: r.resp.Body, release: cancels[r.hedge]}
+
+			return r.resp, nil
+		}
+	}
+}
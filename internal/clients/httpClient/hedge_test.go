@@ -0,0 +1,120 @@
+package httpClient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedging_delay(t *testing.T) {
+	get := httptest.NewRequest(http.MethodGet, "https://upstream/", nil)
+	post := httptest.NewRequest(http.MethodPost, "https://upstream/", nil)
+
+	assert.Nil(t, newHedging(HedgeOptions{}))
+
+	h := newHedging(HedgeOptions{Enabled: true, MinDelay: 10 * time.Millisecond})
+
+	for range minHedgeSamples - 1 {
+		h.observe("upstream", time.Millisecond)
+	}
+
+	_, ok := h.delay(get)
+	assert.False(t, ok, "too few latencies are known")
+
+	h.observe("upstream", time.Millisecond)
+
+	delay, ok := h.delay(get)
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Millisecond, delay, "the minimum delay applies")
+
+	// Fill the ring with 1ms to 100ms, replacing the first samples.
+	for i := 1; i <= hedgeSamples; i++ {
+		h.observe("upstream", time.Duration(i)*time.Millisecond)
+	}
+
+	delay, ok = h.delay(get)
+	require.True(t, ok)
+	assert.Equal(t, 95*time.Millisecond, delay)
+
+	_, ok = h.delay(post)
+	assert.False(t, ok, "non-idempotent requests are not hedged")
+}
+
+func TestClient_Do_hedge(t *testing.T) {
+	var requests atomic.Int32
+
+	canceled := make(chan struct{})
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-r.Context().Done()
+			close(canceled)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"from":"hedge"}`))
+	}, ClientOptions{Hedge: HedgeOptions{Enabled: true}})
+
+	for range minHedgeSamples {
+		client.hedging.observe(client.BaseURL.Host, 10*time.Millisecond)
+	}
+
+	body, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"from":"hedge"}`, string(body))
+	assert.Equal(t, int32(2), requests.Load())
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the slower request was not canceled")
+	}
+
+	// A POST is never hedged.
+	requests.Store(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	canceled = make(chan struct{})
+	_, err = client.Do(ctx, http.MethodPost, "/", nil, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestClient_Do_hedgeFailed(t *testing.T) {
+	var requests atomic.Int32
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		// Drop the connection without a response.
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}, ClientOptions{Hedge: HedgeOptions{Enabled: true}})
+
+	for range minHedgeSamples {
+		client.hedging.observe(client.BaseURL.Host, 10*time.Millisecond)
+	}
+
+	failed := hedgeCounter.WithLabelValues(client.BaseURL.Host, "failed")
+	before := testutil.ToFloat64(failed)
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, before+1, testutil.ToFloat64(failed))
+	assert.Zero(t, testutil.ToFloat64(hedgeCounter.WithLabelValues(client.BaseURL.Host, "lost")))
+}
@@ -2,6 +2,7 @@ package httpClient
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -13,75 +14,99 @@ import (
 	"net/url"
 	"time"
 
-	"go-template/pkg/logger"
 	"go-template/pkg/tracer"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
-	"go.uber.org/zap"
-	"golang.org/x/net/proxy"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type ClientOptions struct {
-	BaseURL            *url.URL
-	Sock5Proxy         string
+	BaseURL *url.URL
+	// Socks5Proxy is a SOCKS5 proxy as host:port or
+	// socks5://[user:password@]host:port; socks5h:// resolves names on the
+	// proxy.
+	Socks5Proxy string
+	// HTTPProxy is a proxy reached with HTTP CONNECT for HTTPS targets, as
+	// http(s)://[user:password@]host:port. At most one proxy may be set;
+	// without either, the HTTP_PROXY family of environment variables applies.
+	HTTPProxy string
+	// NoProxy lists, comma-separated, the hosts, domains, IP addresses and
+	// CIDR ranges reached without the proxy, as in NO_PROXY.
+	NoProxy            string
 	InsecureSkipVerify bool
+	TLS                TLSOptions
+	RetryPolicy        RetryPolicy
+	Breaker            BreakerOptions
+	// Limit bounds the rate and concurrency of all requests of the client;
+	// PathLimits additionally bound those whose path is a prefix or lies
+	// under it, resolved against BaseURL: "item" covers "item/1" but not
+	// "items". Only the longest matching prefix applies. SetLimit replaces
+	// Limit at run time.
+	Limit      LimitOptions
+	PathLimits map[string]LimitOptions
+	// MaxResponseBytes bounds the response bodies read by Do. Zero means
+	// DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+	Timeouts         Timeouts
+	Hedge            HedgeOptions
+	// Cache, when set, stores the responses of GET requests as allowed by
+	// their Cache-Control headers.
+	Cache Cache
 }
 
 type Client struct {
-	c       *http.Client
-	BaseURL *url.URL
+	c        *http.Client
+	BaseURL  *url.URL
+	retry    RetryPolicy
+	breakers *breakers
+	limits   *limits
+	cache    *responseCache
+	timeout  Timeouts
+	hedging  *hedging
+	maxBody  int64
 }
 
-func NewClient(options ClientOptions) *Client {
-	transport := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: false},
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		ForceAttemptHTTP2:     true,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-	}
-
-	if options.InsecureSkipVerify {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// NewClient returns a client for the upstream at options.BaseURL. It fails
+// when the proxy settings are invalid or the TLS files cannot be loaded.
+func NewClient(options ClientOptions) (*Client, error) {
+	proxy, err := proxyFunc(options)
+	if err != nil {
+		return nil, err
 	}
 
-	if options.Sock5Proxy != "" {
-		dialSocksProxy, err := proxy.SOCKS5("tcp", "localhost:11337", nil, proxy.Direct)
-		if err != nil {
-			fmt.Println("Error connecting to proxy:", err)
-		}
-
-		if contextDialer, ok := dialSocksProxy.(proxy.ContextDialer); ok {
-			transport.DialContext = contextDialer.DialContext
-		} else {
-			logger.Fatal("Error connecting to proxy:", zap.Error(errors.New("Failed type assertion to DialContext")))
-
-			return nil
-		}
+	transport, err := newTransport(options, proxy)
+	if err != nil {
+		return nil, err
 	}
 
 	// Wrap the transport with OpenTelemetry instrumentation
 	httpTransport := otelhttp.NewTransport(transport)
 
+	// Deadlines come from Timeouts, per call and per attempt.
 	c := http.Client{
 		Transport: httpTransport,
-		Timeout:   10 * time.Second,
 	}
 
 	return &Client{
-		c:       &c,
-		BaseURL: options.BaseURL,
-	}
+		c:        &c,
+		BaseURL:  options.BaseURL,
+		retry:    options.RetryPolicy,
+		breakers: newBreakers(options.Breaker),
+		limits:   newLimits(options),
+		cache:    newResponseCache(options.Cache),
+		timeout:  options.Timeouts,
+		hedging:  newHedging(options.Hedge),
+		maxBody:  cmp.Or(options.MaxResponseBytes, DefaultMaxResponseBytes),
+	}, nil
 }
 
+// Do sends a request to path, relative to BaseURL, with body encoded as JSON
+// and args as query parameters, and returns the response body. Responses
+// with a non-2xx status are returned as a *StatusError. With a Cache, GET
+// requests are served from it while their responses are fresh. The call is
+// bounded by the client's Timeouts, or those set with WithTimeouts.
 func (client Client) Do(ctx context.Context, method, path string, body any, args map[string]string) ([]byte, error) {
 	// Start a new span for the HTTP request
 	spanName := fmt.Sprintf("HTTP %s %s", method, path)
@@ -92,6 +117,9 @@ func (client Client) Do(ctx context.Context, method, path string, body any, args
 	)
 	defer span.End()
 
+	ctx, cancel := context.WithTimeout(ctx, client.timeouts(ctx).Call)
+	defer cancel()
+
 	request, err := client.newRequest(ctx, method, path, body, args)
 	if err != nil {
 		span.RecordError(err)
@@ -99,12 +127,33 @@ func (client Client) Do(ctx context.Context, method, path string, body any, args
 		return nil, err
 	}
 
-	resp, err := client.c.Do(request)
+	var respBody []byte
+
+	if client.cache != nil && method == http.MethodGet {
+		respBody, err = client.cache.do(ctx, client, request)
+	} else {
+		_, respBody, err = client.roundTrip(ctx, request)
+	}
+
 	if err != nil {
 		span.RecordError(err)
 
 		return nil, err
 	}
+
+	return respBody, nil
+}
+
+// roundTrip sends req and returns the response, with its body read and
+// closed. Non-2xx responses are returned as a *StatusError, except for 304
+// Not Modified answering a conditional request.
+func (client Client) roundTrip(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	span := tracer.SpanFromContext(ctx)
+
+	resp, err := client.send(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
 
 	// Add response attributes to the span
@@ -113,14 +162,171 @@ func (client Client) Do(ctx context.Context, method, path string, body any, args
 		attribute.String("http.status", resp.Status),
 	)
 
-	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotModified && conditional(req) {
+		return resp, nil, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		span.SetStatus(codes.Error, resp.Status)
+
+		return nil, nil, newStatusError(req, resp)
+	}
+
+	respBody, err := readBody(resp, client.maxBody)
 	if err != nil {
+		// The attempt's deadline still runs while its body is read.
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s reading the response", ErrAttemptTimeout, client.timeouts(ctx).Attempt)
+		}
+
+		return nil, nil, err
+	}
+
+	return resp, respBody, nil
+}
+
+func conditional(req *http.Request) bool {
+	return req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+}
+
+// newHTTPTransport returns the transport used for upstream connections.
+func newHTTPTransport(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 proxy,
+		TLSClientConfig:       tlsConfig,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     true,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).DialContext,
+	}
+}
+
+// send performs req, retrying it according to the client's RetryPolicy.
+func (client Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	span := tracer.SpanFromContext(ctx)
+	attempts := client.retry.attempts(req.Method)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := client.hedge(ctx, req, attempt)
+		if attempt >= attempts {
+			return resp, err
+		}
+
+		reason, retry := client.retry.shouldRetry(resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		delay, ok := client.retry.delay(attempt, resp, time.Now())
+		if deadline, hasDeadline := ctx.Deadline(); !ok || hasDeadline && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		if resp != nil {
+			drain(resp)
+		}
+
+		retryCounter.WithLabelValues(req.URL.Host, reason).Inc()
+		span.AddEvent("retry", oteltrace.WithAttributes(
+			attribute.Int("http.attempt", attempt),
+			attribute.String("http.retry_reason", reason),
+			attribute.String("http.retry_delay", delay.String()),
+		))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt sends a copy of req with a fresh body, in a child span, once the
+// client's limits allow it and unless the circuit breaker of the host is
+// open. It is bounded by Timeouts.Attempt until its response body is
+// closed.
+func (client Client) attempt(ctx context.Context, req *http.Request, n int) (*http.Response, error) {
+	ctx, span := tracer.StartSpan(ctx, "HTTP attempt", attribute.Int("http.attempt", n))
+	defer span.End()
+
+	parent := ctx
+
+	var cancel context.CancelFunc = func() {}
+	if timeout := client.timeouts(ctx).Attempt; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	acquired, err := client.limits.acquire(ctx, req)
+	if err != nil {
+		cancel()
 		span.RecordError(err)
 
 		return nil, err
 	}
 
-	return respBody, nil
+	release := func() {
+		acquired(true)
+		cancel()
+	}
+
+	// A request that is not sent gives its rate tokens back, so that a
+	// circuit breaker failing calls fast does not use them up.
+	unsent := func() {
+		acquired(false)
+		cancel()
+	}
+
+	done, err := client.breakers.allow(req.URL.Host)
+	if err != nil {
+		unsent()
+		span.RecordError(err)
+
+		return nil, err
+	}
+
+	r := req.Clone(ctx)
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			unsent()
+			done(ignored)
+
+			return nil, err
+		}
+
+		r.Body = body
+	}
+
+	start := time.Now()
+	resp, err := client.c.Do(r)
+	done(outcomeOf(resp, err))
+
+	if err != nil {
+		release()
+
+		if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s", ErrAttemptTimeout, client.timeouts(ctx).Attempt)
+		}
+
+		span.RecordError(err)
+
+		return nil, err
+	}
+
+	client.hedging.observe(req.URL.Host, time.Since(start))
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	return resp, nil
 }
 
 func (client Client) newRequest(ctx context.Context, method, path string, body any, args map[string]string) (*http.Request, error) {
@@ -34,11 +34,34 @@ func TestNewClient(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "client with socks5 proxy",
+			options: ClientOptions{
+				BaseURL:     &url.URL{Scheme: "https", Host: "example.com"},
+				Socks5Proxy: "localhost:11337",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid proxy",
+			options: ClientOptions{
+				BaseURL:   &url.URL{Scheme: "https", Host: "example.com"},
+				HTTPProxy: "ftp://proxy:21",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := NewClient(tt.options)
+			client, err := NewClient(tt.options)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
 			assert.NotNil(t, client)
 			assert.Equal(t, tt.options.BaseURL, client.BaseURL)
 		})
@@ -97,6 +120,16 @@ func TestClient_Do(t *testing.T) {
 			serverStatus: http.StatusOK,
 			wantErr:      false,
 		},
+		{
+			name:   "server error",
+			method: http.MethodGet,
+			path:   "/test",
+			serverResponse: &testResponse{
+				Message: "failure",
+			},
+			serverStatus: http.StatusInternalServerError,
+			wantErr:      true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -138,9 +171,10 @@ func TestClient_Do(t *testing.T) {
 			require.NoError(t, err)
 
 			// Create client
-			client := NewClient(ClientOptions{
+			client, err := NewClient(ClientOptions{
 				BaseURL: serverURL,
 			})
+			require.NoError(t, err)
 
 			// Make request
 			resp, err := client.Do(context.Background(), tt.method, tt.path, tt.body, tt.args)
@@ -203,9 +237,10 @@ func TestClient_newRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := NewClient(ClientOptions{
+			client, err := NewClient(ClientOptions{
 				BaseURL: &url.URL{Scheme: "https", Host: "example.com"},
 			})
+			require.NoError(t, err)
 
 			req, err := client.newRequest(context.Background(), tt.method, tt.path, tt.body, tt.args)
 
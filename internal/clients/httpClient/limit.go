@@ -0,0 +1,310 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-template/pkg/metrics"
+)
+
+// ErrRateLimited is returned, wrapped, for requests that could not get a
+// rate limiter token or a concurrency slot before their context ended.
+// Check for it with errors.Is.
+var ErrRateLimited = errors.New("client-side request limit exceeded")
+
+var (
+	limitWait = metrics.NewHistogramVec("http_client_limit_wait_seconds", []string{"host", "limit"},
+		"Time requests spent waiting for a rate limiter token or a concurrency slot.")
+	limitRejected = metrics.NewCounterVec("http_client_limit_rejected_total", []string{"host", "limit"},
+		"Requests rejected because no rate limiter token or concurrency slot was available before their deadline.")
+)
+
+// LimitOptions bounds the requests sent to an upstream. The zero value sets
+// no limit.
+type LimitOptions struct {
+	// Rate is the sustained number of requests per second, enforced with a
+	// token bucket. Zero means unlimited.
+	Rate float64
+	// Burst is the number of requests that may be sent at once after a
+	// quiet period. Zero means 1.
+	Burst int
+	// MaxInFlight is the maximum number of requests awaiting or reading a
+	// response. Zero means unlimited.
+	MaxInFlight int
+}
+
+func (o LimitOptions) enabled() bool {
+	return o.Rate > 0 || o.MaxInFlight > 0
+}
+
+// limiter enforces one LimitOptions.
+type limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// slots holds a value per request in flight; nil when unlimited.
+	slots chan struct{}
+}
+
+func newLimiter(opts LimitOptions, now func() time.Time) *limiter {
+	l := &limiter{rate: opts.Rate, burst: float64(max(opts.Burst, 1)), now: now}
+	l.tokens = l.burst
+	l.last = now()
+
+	if opts.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, opts.MaxInFlight)
+	}
+
+	return l
+}
+
+// reserve takes a token and returns how long to wait before it may be
+// used. It takes none and reports false when the wait would end after
+// deadline.
+func (l *limiter) reserve(deadline time.Time) (time.Duration, bool) {
+	if l.rate <= 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+
+	if !deadline.IsZero() && now.Add(wait).After(deadline) {
+		return 0, false
+	}
+
+	l.tokens--
+
+	return wait, true
+}
+
+// unreserve returns a token taken by reserve for a request that was not
+// sent.
+func (l *limiter) unreserve() {
+	if l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	l.tokens = min(l.burst, l.tokens+1)
+	l.mu.Unlock()
+}
+
+// limits holds the client-wide limiter and those of path prefixes.
+type limits struct {
+	// client is nil when the client-wide Limit is disabled. It is replaced
+	// by setClient.
+	client atomic.Pointer[limiter]
+	// paths is ordered by decreasing prefix length, so the first match is
+	// the most specific.
+	paths []pathLimiter
+}
+
+type pathLimiter struct {
+	prefix string
+	*limiter
+}
+
+// matches reports whether path is the prefix or lies under it, so that
+// "/item" applies to "/item/1" but not to "/items".
+func (p pathLimiter) matches(path string) bool {
+	dir := p.prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+
+	return path == p.prefix || strings.HasPrefix(path, dir)
+}
+
+// newLimits returns the limits of options. Path prefixes are resolved
+// against BaseURL like the paths given to Do.
+func newLimits(options ClientOptions) *limits {
+	l := &limits{}
+	l.setClient(options.Limit)
+
+	for prefix, opts := range options.PathLimits {
+		if !opts.enabled() {
+			continue
+		}
+
+		if options.BaseURL != nil {
+			prefix = options.BaseURL.ResolveReference(&url.URL{Path: prefix}).Path
+		}
+
+		l.paths = append(l.paths, pathLimiter{prefix: prefix, limiter: newLimiter(opts, time.Now)})
+	}
+
+	sort.Slice(l.paths, func(i, j int) bool { return len(l.paths[i].prefix) > len(l.paths[j].prefix) })
+
+	return l
+}
+
+// setClient replaces the client-wide limiter for subsequent requests.
+// Requests holding a slot of the previous one release it there, so the
+// new MaxInFlight may be exceeded until they end.
+func (l *limits) setClient(opts LimitOptions) {
+	if !opts.enabled() {
+		l.client.Store(nil)
+
+		return
+	}
+
+	l.client.Store(newLimiter(opts, time.Now))
+}
+
+// match returns the limiters applying to req, the most specific first.
+func (l *limits) match(req *http.Request) []*limiter {
+	var matched []*limiter
+
+	for _, p := range l.paths {
+		if p.matches(req.URL.Path) {
+			matched = append(matched, p.limiter)
+
+			break
+		}
+	}
+
+	if client := l.client.Load(); client != nil {
+		matched = append(matched, client)
+	}
+
+	return matched
+}
+
+// acquire waits until req may be sent under every limit applying to it.
+// When it returns no error, release must be called once the response has
+// been read, with sent false when the request was not sent after all so
+// that its rate tokens are given back.
+func (l *limits) acquire(ctx context.Context, req *http.Request) (release func(sent bool), err error) {
+	limiters := l.match(req)
+	if len(limiters) == 0 {
+		return func(bool) {}, nil
+	}
+
+	host := req.URL.Host
+	deadline, _ := ctx.Deadline()
+
+	var (
+		wait  time.Duration
+		rated bool
+	)
+
+	for i, lim := range limiters {
+		rated = rated || lim.rate > 0
+
+		d, ok := lim.reserve(deadline)
+		if !ok {
+			for _, taken := range limiters[:i] {
+				taken.unreserve()
+			}
+
+			limitRejected.WithLabelValues(host, "rate").Inc()
+
+			return nil, fmt.Errorf("%w: no rate limiter token for %s before the deadline", ErrRateLimited, host)
+		}
+
+		wait = max(wait, d)
+	}
+
+	if rated {
+		limitWait.WithLabelValues(host, "rate").Observe(wait.Seconds())
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			for _, lim := range limiters {
+				lim.unreserve()
+			}
+
+			limitRejected.WithLabelValues(host, "rate").Inc()
+
+			return nil, fmt.Errorf("%w: no rate limiter token for %s: %w", ErrRateLimited, host, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	start := time.Now()
+
+	var held []*limiter
+
+	release = func(sent bool) {
+		for _, lim := range held {
+			<-lim.slots
+		}
+
+		if !sent {
+			for _, lim := range limiters {
+				lim.unreserve()
+			}
+		}
+	}
+
+	for _, lim := range limiters {
+		if lim.slots == nil {
+			continue
+		}
+
+		select {
+		case lim.slots <- struct{}{}:
+			held = append(held, lim)
+		case <-ctx.Done():
+			release(false)
+			limitRejected.WithLabelValues(host, "concurrency").Inc()
+
+			return nil, fmt.Errorf("%w: no concurrency slot for %s: %w", ErrRateLimited, host, ctx.Err())
+		}
+	}
+
+	if len(held) > 0 {
+		limitWait.WithLabelValues(host, "concurrency").Observe(time.Since(start).Seconds())
+	}
+
+	return release, nil
+}
+
+// SetLimit replaces the client-wide Limit for subsequent requests, as on a
+// configuration reload. PathLimits are left as they are.
+func (client Client) SetLimit(opts LimitOptions) {
+	client.limits.setClient(opts)
+}
+
+// releaseBody calls release once body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
@@ -0,0 +1,222 @@
+package httpClient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_reserve(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := newLimiter(LimitOptions{Rate: 2, Burst: 2}, clock.now)
+
+	for range 2 {
+		wait, ok := l.reserve(time.Time{})
+		require.True(t, ok)
+		assert.Zero(t, wait)
+	}
+
+	// The bucket is empty: the next token comes in half a second.
+	_, ok := l.reserve(clock.t.Add(100 * time.Millisecond))
+	assert.False(t, ok, "a token later than the deadline is refused")
+
+	wait, ok := l.reserve(clock.t.Add(time.Second))
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// The reserved token is owed, so the following one comes a second in.
+	wait, ok = l.reserve(time.Time{})
+	require.True(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	l.unreserve()
+	l.unreserve()
+
+	clock.t = clock.t.Add(time.Second)
+
+	wait, ok = l.reserve(time.Time{})
+	require.True(t, ok)
+	assert.Zero(t, wait)
+}
+
+func TestNewLimits(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://upstream.example/item/1", nil)
+	assert.Empty(t, newLimits(ClientOptions{}).match(req))
+	assert.Empty(t, newLimits(ClientOptions{PathLimits: map[string]LimitOptions{"item/": {}}}).match(req))
+
+	base, err := url.Parse("https://upstream.example/v0/")
+	require.NoError(t, err)
+
+	l := newLimits(ClientOptions{
+		BaseURL: base,
+		Limit:   LimitOptions{MaxInFlight: 10},
+		PathLimits: map[string]LimitOptions{
+			"item/":       {Rate: 1},
+			"item/story/": {Rate: 2},
+			"user":        {Rate: 3},
+		},
+	})
+	client := l.client.Load()
+
+	tests := []struct {
+		path string
+		want []*limiter
+	}{
+		{"/v0/item/story/1", []*limiter{l.paths[0].limiter, client}},
+		{"/v0/item/1", []*limiter{l.paths[1].limiter, client}},
+		{"/v0/user", []*limiter{l.paths[2].limiter, client}},
+		{"/v0/user/1", []*limiter{l.paths[2].limiter, client}},
+		{"/v0/users/1", []*limiter{client}},
+		{"/v0/topstories", []*limiter{client}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://upstream.example"+tt.path, nil)
+			assert.Equal(t, tt.want, l.match(req))
+		})
+	}
+}
+
+func TestLimits_acquire(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &limits{}
+	l.client.Store(newLimiter(LimitOptions{Rate: 1, Burst: 2, MaxInFlight: 1}, clock.now))
+	req := httptest.NewRequest(http.MethodGet, "https://upstream.example/", nil)
+
+	release, err := l.acquire(context.Background(), req)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = l.acquire(ctx, req)
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	release(true)
+
+	// The token of the request that got no slot was given back.
+	assert.InDelta(t, 1, l.client.Load().tokens, 1e-9)
+}
+
+func TestClient_Do_rateLimit(t *testing.T) {
+	var requests atomic.Int32
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{}`))
+	}, ClientOptions{
+		Limit:       LimitOptions{Rate: 10, Burst: 1},
+		RetryPolicy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+
+	// The next token comes in 100ms, after the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = client.Do(ctx, http.MethodGet, "/", nil, nil)
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	// Without a deadline the request waits for its token.
+	start := time.Now()
+	_, err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestClient_Do_maxInFlight(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-unblock
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}, ClientOptions{Limit: LimitOptions{MaxInFlight: 1}})
+
+	slow := make(chan error)
+
+	go func() {
+		_, err := client.Do(context.Background(), http.MethodGet, "/slow", nil, nil)
+		slow <- err
+	}()
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Do(ctx, http.MethodGet, "/fast", nil, nil)
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(unblock)
+	require.NoError(t, <-slow)
+
+	// The slot was released when the slow response was read.
+	_, err = client.Do(context.Background(), http.MethodGet, "/fast", nil, nil)
+	require.NoError(t, err)
+}
+
+func TestClient_SetLimit(t *testing.T) {
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}, ClientOptions{})
+
+	do := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Do(ctx, http.MethodGet, "/", nil, nil)
+
+		return err
+	}
+
+	require.NoError(t, do())
+	require.NoError(t, do())
+
+	// The next token comes in a second, after the deadline.
+	client.SetLimit(LimitOptions{Rate: 1, Burst: 1})
+
+	require.NoError(t, do())
+	require.ErrorIs(t, do(), ErrRateLimited)
+
+	client.SetLimit(LimitOptions{})
+
+	require.NoError(t, do())
+}
+
+func TestClient_Do_breakerOpenKeepsTokens(t *testing.T) {
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, ClientOptions{
+		Limit:   LimitOptions{Rate: 0.001, Burst: 3},
+		Breaker: BreakerOptions{ConsecutiveFailures: 1, OpenTimeout: time.Minute},
+	})
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.Error(t, err)
+
+	for range 5 {
+		_, err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	// Only the request that was sent took a token.
+	assert.InDelta(t, 2, client.limits.client.Load().tokens, 0.01)
+}
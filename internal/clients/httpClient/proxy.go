@@ -0,0 +1,77 @@
+package httpClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyFunc returns the Transport.Proxy function for options. Without an
+// explicit proxy the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables apply.
+func proxyFunc(options ClientOptions) (func(*http.Request) (*url.URL, error), error) {
+	if options.Socks5Proxy != "" && options.HTTPProxy != "" {
+		return nil, errors.New("only one of the SOCKS5 and HTTP proxies may be set")
+	}
+
+	var (
+		proxyURL *url.URL
+		err      error
+	)
+
+	switch {
+	case options.Socks5Proxy != "":
+		proxyURL, err = parseProxy(options.Socks5Proxy, "socks5", "socks5", "socks5h")
+	case options.HTTPProxy != "":
+		proxyURL, err = parseProxy(options.HTTPProxy, "http", "http", "https")
+	default:
+		return http.ProxyFromEnvironment, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// httpproxy implements the NO_PROXY rules: host names match themselves
+	// and their subdomains, and IP addresses, CIDR ranges, ports and "*"
+	// are supported.
+	cfg := httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    options.NoProxy,
+	}
+	proxy := cfg.ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// parseProxy parses a proxy given as a URL with one of schemes, which may
+// carry credentials, or as a bare host:port using defaultScheme.
+func parseProxy(raw, defaultScheme string, schemes ...string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = defaultScheme + "://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+
+	if u.Host == "" || u.Port() == "" {
+		return nil, fmt.Errorf("invalid proxy %s: want host:port", u.Redacted())
+	}
+
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return u, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid proxy %s: scheme must be one of %s", u.Redacted(), strings.Join(schemes, ", "))
+}
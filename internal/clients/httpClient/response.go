@@ -0,0 +1,99 @@
+package httpClient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxResponseBytes bounds the response bodies read by Do when
+	// ClientOptions.MaxResponseBytes is 0.
+	DefaultMaxResponseBytes = 10 << 20
+
+	// maxErrorBodyBytes bounds the body kept in a StatusError.
+	maxErrorBodyBytes = 1 << 10
+)
+
+// ErrResponseTooLarge is returned, wrapped, when a response body exceeds
+// the client's maximum response size.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// StatusError is returned by Do for responses with a non-2xx status. Body
+// holds at most the first KiB of the response body.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+
+	if snippet := strings.TrimSpace(strings.ToValidUTF8(string(e.Body), "")); snippet != "" {
+		msg += ": " + snippet
+	}
+
+	return msg
+}
+
+// newStatusError reads the start of resp's body into a StatusError.
+func newStatusError(req *http.Request, resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+
+	return &StatusError{
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
+
+// readBody reads resp's body, failing with ErrResponseTooLarge beyond limit
+// bytes.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d", ErrResponseTooLarge, resp.ContentLength, limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, limit)
+	}
+
+	return body, nil
+}
+
+// DoJSON performs the request like client.Do and decodes the JSON response
+// into a T. An empty response body decodes to the zero T.
+func DoJSON[T any](ctx context.Context, client *Client, method, path string, body any, args map[string]string) (T, error) {
+	var out T
+
+	data, err := client.Do(ctx, method, path, body, args)
+	if err != nil {
+		return out, err
+	}
+
+	if len(data) == 0 {
+		return out, nil
+	}
+
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+
+	return out, nil
+}
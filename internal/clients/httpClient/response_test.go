@@ -0,0 +1,126 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServerClient(t *testing.T, handler http.HandlerFunc, options ClientOptions) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	options.BaseURL = serverURL
+
+	client, err := NewClient(options)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestClient_Do_statusError(t *testing.T) {
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"no such item"}` + strings.Repeat(" ", 2*maxErrorBodyBytes)))
+	}, ClientOptions{})
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/item/1", nil, nil)
+
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, "abc", statusErr.Header.Get("X-Request-Id"))
+	assert.Len(t, statusErr.Body, maxErrorBodyBytes)
+	assert.Equal(t, "GET "+client.BaseURL.String()+`/item/1: 404 Not Found: {"error":"no such item"}`, err.Error())
+}
+
+func TestClient_Do_maxResponseBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "content length",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(strings.Repeat("x", 11)))
+			},
+		},
+		{
+			name: "chunked",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(strings.Repeat("x", 6)))
+				w.(http.Flusher).Flush()
+				_, _ = w.Write([]byte(strings.Repeat("x", 5)))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServerClient(t, tt.handler, ClientOptions{MaxResponseBytes: 10})
+
+			_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+			assert.ErrorIs(t, err, ErrResponseTooLarge)
+		})
+	}
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 10)))
+	}, ClientOptions{MaxResponseBytes: 10})
+
+	body, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, body, 10)
+}
+
+func TestDoJSON(t *testing.T) {
+	type item struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}
+
+	client := newTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/item":
+			_, _ = w.Write([]byte(`{"id": 8863, "title": "My YC app"}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/broken":
+			_, _ = w.Write([]byte(`{"id": "not a number"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}, ClientOptions{})
+
+	ctx := context.Background()
+
+	got, err := DoJSON[item](ctx, client, http.MethodGet, "/item", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, item{ID: 8863, Title: "My YC app"}, got)
+
+	got, err = DoJSON[item](ctx, client, http.MethodGet, "/empty", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, item{}, got)
+
+	_, err = DoJSON[item](ctx, client, http.MethodGet, "/broken", nil, nil)
+	assert.ErrorContains(t, err, "failed to decode GET /broken response")
+
+	_, err = DoJSON[*item](ctx, client, http.MethodGet, "/down", nil, nil)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+}
@@ -0,0 +1,166 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go-template/pkg/metrics"
+)
+
+// maxDrainBytes bounds how much of a retried response is read so that its
+// connection can be reused.
+const maxDrainBytes = 64 << 10
+
+// DefaultRetryableStatusCodes are the responses retried when
+// RetryPolicy.RetryableStatusCodes is nil.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+var retryCounter = metrics.NewCounterVec("http_client_retries_total", []string{"host", "reason"},
+	"Outbound HTTP requests retried, by upstream host and reason (a status code or error).")
+
+// RetryPolicy controls how Client.Do retries failed requests. The zero value
+// makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles with every
+	// further retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction, between 0 and 1, of each delay that is
+	// randomized so that clients do not retry in lockstep.
+	Jitter float64
+	// RetryableStatusCodes lists the responses that are retried. Nil means
+	// DefaultRetryableStatusCodes. Transport errors are always retried, but
+	// requests refused by an open circuit breaker are not.
+	RetryableStatusCodes []int
+	// RetryNonIdempotent also retries POST and PATCH requests, which may
+	// then be applied more than once.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy makes up to three attempts with a jittered exponential
+// backoff between 100ms and 2s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Jitter:      0.5,
+	}
+}
+
+// attempts returns how many attempts a request with method may get.
+func (p RetryPolicy) attempts(method string) int {
+	if p.MaxAttempts <= 1 || !p.RetryNonIdempotent && !idempotent(method) {
+		return 1
+	}
+
+	return p.MaxAttempts
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying,
+// along with the reason recorded in the retry counter.
+func (p RetryPolicy) shouldRetry(resp *http.Response, err error) (string, bool) {
+	if err != nil {
+		if errors.Is(err, ErrAttemptTimeout) {
+			return "timeout", true
+		}
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) ||
+			errors.Is(err, ErrRateLimited) {
+			return "", false
+		}
+
+		return "error", true
+	}
+
+	codes := p.RetryableStatusCodes
+	if codes == nil {
+		codes = DefaultRetryableStatusCodes
+	}
+
+	if slices.Contains(codes, resp.StatusCode) {
+		return strconv.Itoa(resp.StatusCode), true
+	}
+
+	return "", false
+}
+
+// delay returns the wait before retrying after the given attempt. A
+// Retry-After header lengthens the wait; when it asks for more than
+// MaxDelay, delay reports false and the response is returned as is.
+func (p RetryPolicy) delay(attempt int, resp *http.Response, now time.Time) (time.Duration, bool) {
+	d := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 && d > 0 {
+		d -= time.Duration(jitter * rand.Float64() * float64(d))
+	}
+
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			if p.MaxDelay > 0 && after > p.MaxDelay {
+				return 0, false
+			}
+
+			d = max(d, after)
+		}
+	}
+
+	return d, true
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP
+// date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+
+		return time.Duration(secs) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(t.Sub(now), 0), true
+}
+
+// idempotent reports whether requests with method may safely be repeated,
+// as defined by RFC 9110.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// drain discards the rest of a response that will not be used, so that its
+// connection goes back to the pool.
+func drain(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+	resp.Body.Close()
+}
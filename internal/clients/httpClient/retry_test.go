@@ -0,0 +1,191 @@
+package httpClient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Do_retry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	tests := []struct {
+		name         string
+		method       string
+		policy       RetryPolicy
+		statuses     []int
+		retryAfter   string
+		wantAttempts int32
+		wantStatus   int
+	}{
+		{
+			name:         "retries until success",
+			method:       http.MethodGet,
+			policy:       policy,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			wantAttempts: 3,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "gives up after max attempts",
+			method:       http.MethodGet,
+			policy:       policy,
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantAttempts: 3,
+			wantStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:         "does not retry other statuses",
+			method:       http.MethodGet,
+			policy:       policy,
+			statuses:     []int{http.StatusInternalServerError},
+			wantAttempts: 1,
+			wantStatus:   http.StatusInternalServerError,
+		},
+		{
+			name:         "does not retry non-idempotent methods",
+			method:       http.MethodPost,
+			policy:       policy,
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantAttempts: 1,
+			wantStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:   "retries non-idempotent methods when allowed",
+			method: http.MethodPost,
+			policy: RetryPolicy{
+				MaxAttempts: 2, BaseDelay: time.Millisecond, RetryNonIdempotent: true,
+			},
+			statuses:     []int{http.StatusTooManyRequests, http.StatusCreated},
+			wantAttempts: 2,
+			wantStatus:   http.StatusCreated,
+		},
+		{
+			name:         "zero policy makes one attempt",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantAttempts: 1,
+			wantStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:         "Retry-After beyond max delay stops retrying",
+			method:       http.MethodGet,
+			policy:       policy,
+			statuses:     []int{http.StatusTooManyRequests},
+			retryAfter:   "120",
+			wantAttempts: 1,
+			wantStatus:   http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+
+				if r.Method == http.MethodPost {
+					assert.JSONEq(t, `{"key":"value"}`, string(body))
+				}
+
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client, err := NewClient(ClientOptions{BaseURL: serverURL, RetryPolicy: tt.policy})
+			require.NoError(t, err)
+
+			var body any
+			if tt.method == http.MethodPost {
+				body = map[string]string{"key": "value"}
+			}
+
+			request, err := client.newRequest(context.Background(), tt.method, "/test", body, nil)
+			require.NoError(t, err)
+
+			resp, err := client.send(context.Background(), request)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 70: time.Second} {
+		d, ok := p.delay(attempt, nil, now)
+		assert.True(t, ok)
+		assert.Equal(t, want, d, "attempt %d", attempt)
+	}
+
+	p.Jitter = 0.5
+
+	for range 100 {
+		d, _ := p.delay(1, nil, now)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {now.Add(time.Second).Format(http.TimeFormat)}}}
+	d, ok := p.delay(1, resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+
+	resp.Header.Set("Retry-After", "1")
+	d, ok = p.delay(1, resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+
+	resp.Header.Set("Retry-After", "2")
+	_, ok = p.delay(1, resp, now)
+	assert.False(t, ok)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"Wed, 01 Jan 2025 00:00:10 GMT", 10 * time.Second, true},
+		{"Tue, 31 Dec 2024 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package httpClient
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultTimeout bounds a call when Timeouts.Call is 0.
+const DefaultTimeout = 10 * time.Second
+
+// ErrAttemptTimeout is returned, wrapped, when an attempt exceeds
+// Timeouts.Attempt. Unlike the call's deadline, it leaves time to retry.
+var ErrAttemptTimeout = errors.New("attempt timed out")
+
+// Timeouts bounds the time spent on the requests of a Client. A deadline
+// of the caller's context applies as well, whichever ends first.
+type Timeouts struct {
+	// Call bounds a call to Do as a whole, retries and reading the response
+	// included. Zero means DefaultTimeout.
+	Call time.Duration
+	// Attempt bounds each attempt, so that a stalled one is abandoned and
+	// retried while the call's deadline allows. Zero means only Call
+	// applies.
+	Attempt time.Duration
+}
+
+type timeoutsKey struct{}
+
+// WithTimeouts returns a context whose calls use t instead of the client's
+// Timeouts. Zero fields keep the client's values.
+func WithTimeouts(ctx context.Context, t Timeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, t)
+}
+
+// timeouts returns the Timeouts applying to the calls made with ctx.
+func (client Client) timeouts(ctx context.Context) Timeouts {
+	t, _ := ctx.Value(timeoutsKey{}).(Timeouts)
+
+	return Timeouts{
+		Call:    cmp.Or(t.Call, client.timeout.Call, DefaultTimeout),
+		Attempt: cmp.Or(t.Attempt, client.timeout.Attempt),
+	}
+}
@@ -0,0 +1,102 @@
+package httpClient
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_timeouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		client Timeouts
+		ctx    *Timeouts
+		want   Timeouts
+	}{
+		{"defaults", Timeouts{}, nil, Timeouts{Call: DefaultTimeout}},
+		{"client", Timeouts{Call: time.Minute, Attempt: time.Second}, nil, Timeouts{Call: time.Minute, Attempt: time.Second}},
+		{"context overrides", Timeouts{Call: time.Minute, Attempt: time.Second}, &Timeouts{Attempt: 2 * time.Second}, Timeouts{Call: time.Minute, Attempt: 2 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = WithTimeouts(ctx, *tt.ctx)
+			}
+
+			assert.Equal(t, tt.want, Client{timeout: tt.client}.timeouts(ctx))
+		})
+	}
+}
+
+// stallFirst returns a handler stalling its first request until it is
+// canceled, and answering the others right away.
+func stallFirst(requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func TestClient_Do_attemptTimeout(t *testing.T) {
+	var requests atomic.Int32
+
+	client := newTestServerClient(t, stallFirst(&requests), ClientOptions{
+		Timeouts:    Timeouts{Attempt: 50 * time.Millisecond},
+		RetryPolicy: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+
+	body, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err, "the stalled attempt is abandoned and retried")
+	assert.Equal(t, `{}`, string(body))
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Without retries, the attempt timeout is reported.
+	requests.Store(0)
+
+	ctx := WithTimeouts(context.Background(), Timeouts{Attempt: 20 * time.Millisecond})
+	_, err = client.Do(ctx, http.MethodPost, "/", nil, nil)
+	require.ErrorIs(t, err, ErrAttemptTimeout)
+}
+
+func TestClient_Do_attemptTimeoutReadingBody(t *testing.T) {
+	client := newTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"partial":`))
+		http.NewResponseController(w).Flush()
+
+		<-r.Context().Done()
+	}, ClientOptions{Timeouts: Timeouts{Attempt: 20 * time.Millisecond}})
+
+	_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.ErrorIs(t, err, ErrAttemptTimeout)
+}
+
+func TestClient_Do_callTimeout(t *testing.T) {
+	var requests atomic.Int32
+
+	client := newTestServerClient(t, stallFirst(&requests), ClientOptions{
+		Timeouts:    Timeouts{Call: 50 * time.Millisecond},
+		RetryPolicy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+
+	start := time.Now()
+	_, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrAttemptTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), requests.Load(), "the call's deadline leaves no time to retry")
+}
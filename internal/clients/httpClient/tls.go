@@ -0,0 +1,307 @@
+package httpClient
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-template/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTLSReloadInterval is how often the certificate files are checked for
+// changes when TLSOptions.ReloadInterval is 0.
+const DefaultTLSReloadInterval = time.Minute
+
+// ErrCertificatePin is returned, wrapped, when the server's certificate
+// chain matches none of TLSOptions.PinnedSHA256.
+var ErrCertificatePin = errors.New("server certificate matches no pinned key")
+
+// TLSOptions configures the TLS connections of a Client. Certificate files
+// are re-read when they change, so rotated certificates are picked up
+// without a restart.
+type TLSOptions struct {
+	// CertFile and KeyFile hold the PEM client certificate and key presented
+	// to servers requiring mutual TLS.
+	CertFile string
+	KeyFile  string
+	// CAFiles are PEM bundles of the root certificates trusted for servers,
+	// replacing the system roots.
+	CAFiles []string
+	// MinVersion is the minimum TLS version, such as tls.VersionTLS13. Zero
+	// means TLS 1.2.
+	MinVersion uint16
+	// ServerName overrides the name verified in the server certificate and
+	// sent with SNI, for servers reached by IP address or an alias.
+	ServerName string
+	// PinnedSHA256 lists base64 SHA-256 hashes of the SubjectPublicKeyInfo of
+	// certificates, as in HPKP; the server's verified chain must contain
+	// one of them. With InsecureSkipVerify, the certificates the server
+	// presents must.
+	PinnedSHA256 []string
+	// ReloadInterval is how often the files are checked for changes. Zero
+	// means DefaultTLSReloadInterval, a negative value disables reloading.
+	ReloadInterval time.Duration
+}
+
+// ParseTLSVersion parses a TLS version given as "1.0" to "1.3".
+func ParseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unknown TLS version %q, want 1.0, 1.1, 1.2 or 1.3", v)
+}
+
+// parsePins decodes TLSOptions.PinnedSHA256.
+func parsePins(pinned []string) ([][]byte, error) {
+	pins := make([][]byte, len(pinned))
+
+	for i, p := range pinned {
+		pin, err := base64.StdEncoding.DecodeString(p)
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate pin %q: want a base64 SHA-256 hash", p)
+		}
+
+		pins[i] = pin
+	}
+
+	return pins, nil
+}
+
+// tlsConfig returns the client TLS configuration for opts using the given
+// client certificate and roots, either of which may be nil.
+func tlsConfig(opts TLSOptions, insecureSkipVerify bool, pins [][]byte, cert *tls.Certificate, roots *x509.CertPool) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:         cmp.Or(opts.MinVersion, tls.VersionTLS12),
+		ServerName:         opts.ServerName,
+		RootCAs:            roots,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec // Opt-in for tests and local development.
+	}
+
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+
+	if len(pins) > 0 {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			// Without verification there is no verified chain, but the
+			// pins still apply to the certificates the server presents.
+			if insecureSkipVerify {
+				return checkPins([][]*x509.Certificate{cs.PeerCertificates}, pins)
+			}
+
+			return checkPins(cs.VerifiedChains, pins)
+		}
+	}
+
+	return cfg
+}
+
+// newTransport returns the transport for options. When certificate files
+// are configured and reloading is enabled, it is a reloadingTransport.
+func newTransport(options ClientOptions, proxy func(*http.Request) (*url.URL, error)) (http.RoundTripper, error) {
+	opts := options.TLS
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("TLS client certificate and key must be given together")
+	}
+
+	pins, err := parsePins(opts.PinnedSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	files := &tlsFiles{opts: opts, now: time.Now}
+
+	cert, roots, err := files.load()
+	if err != nil {
+		return nil, err
+	}
+
+	build := func(cert *tls.Certificate, roots *x509.CertPool) *http.Transport {
+		return newHTTPTransport(proxy, tlsConfig(opts, options.InsecureSkipVerify, pins, cert, roots))
+	}
+
+	if len(files.names()) == 0 || opts.ReloadInterval < 0 {
+		return build(cert, roots), nil
+	}
+
+	t := &reloadingTransport{files: files, build: build}
+	t.current.Store(build(cert, roots))
+
+	return t, nil
+}
+
+// checkPins reports ErrCertificatePin unless a certificate of the verified
+// chains has a pinned public key.
+func checkPins(chains [][]*x509.Certificate, pins [][]byte) error {
+	if len(pins) == 0 {
+		return nil
+	}
+
+	for _, chain := range chains {
+		for _, c := range chain {
+			sum := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+			if slices.ContainsFunc(pins, func(p []byte) bool { return bytes.Equal(p, sum[:]) }) {
+				return nil
+			}
+		}
+	}
+
+	return ErrCertificatePin
+}
+
+// tlsFiles loads the certificates named by TLSOptions and tracks the
+// modification times of their files.
+type tlsFiles struct {
+	opts TLSOptions
+	now  func() time.Time
+
+	mu        sync.Mutex
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// load reads the client certificate and the roots.
+func (f *tlsFiles) load() (*tls.Certificate, *x509.CertPool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastCheck = f.now()
+
+	// Stat first, so that a file replaced while loading is loaded again.
+	modTimes := make(map[string]time.Time)
+
+	for _, name := range f.names() {
+		if info, err := os.Stat(name); err == nil {
+			modTimes[name] = info.ModTime()
+		}
+	}
+
+	var cert *tls.Certificate
+
+	if f.opts.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(f.opts.CertFile, f.opts.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+
+		cert = &c
+	}
+
+	var roots *x509.CertPool
+
+	if len(f.opts.CAFiles) > 0 {
+		roots = x509.NewCertPool()
+
+		for _, name := range f.opts.CAFiles {
+			pem, err := os.ReadFile(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, nil, fmt.Errorf("no certificates found in CA bundle %s", name)
+			}
+		}
+	}
+
+	f.modTimes = modTimes
+
+	return cert, roots, nil
+}
+
+// due reports whether the files changed, checking at most once per reload
+// interval.
+func (f *tlsFiles) due() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.now().Sub(f.lastCheck) < cmp.Or(f.opts.ReloadInterval, DefaultTLSReloadInterval) {
+		return false
+	}
+
+	f.lastCheck = f.now()
+
+	for _, name := range f.names() {
+		info, err := os.Stat(name)
+		if err != nil || !info.ModTime().Equal(f.modTimes[name]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *tlsFiles) names() []string {
+	var names []string
+
+	if f.opts.CertFile != "" {
+		names = append(names, f.opts.CertFile, f.opts.KeyFile)
+	}
+
+	return append(names, f.opts.CAFiles...)
+}
+
+// reloadingTransport sends requests through a transport that is replaced,
+// along with its connections, when the certificate files change. A failed
+// reload is logged and the previous transport kept.
+type reloadingTransport struct {
+	files *tlsFiles
+	build func(*tls.Certificate, *x509.CertPool) *http.Transport
+
+	mu      sync.Mutex
+	current atomic.Pointer[http.Transport]
+}
+
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.files.due() {
+		t.reload()
+	}
+
+	return t.current.Load().RoundTrip(req)
+}
+
+func (t *reloadingTransport) reload() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cert, roots, err := t.files.load()
+	if err != nil {
+		logger.Error("Failed to reload TLS certificates, keeping the previous ones", zap.Error(err))
+
+		return
+	}
+
+	old := t.current.Swap(t.build(cert, roots))
+	old.CloseIdleConnections()
+
+	logger.Info("Reloaded TLS certificates")
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// current transport.
+func (t *reloadingTransport) CloseIdleConnections() {
+	t.current.Load().CloseIdleConnections()
+}
@@ -0,0 +1,299 @@
+package httpClient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate with its key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, parent *testCert, tmpl x509.Certificate) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := &tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return newTestCert(t, nil, x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+}
+
+func (c *testCert) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.cert)
+
+	return pool
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// write stores the certificate and, if keyFile is not empty, its key as PEM.
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	}
+}
+
+func (c *testCert) pin() string {
+	sum := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type tlsFixture struct {
+	dir      string
+	serverCA *testCert
+	server   *testCert
+	clientCA *testCert
+	url      *url.URL
+}
+
+// newTLSFixture starts a server for upstream.internal requiring a client
+// certificate signed by clientCA.
+func newTLSFixture(t *testing.T) *tlsFixture {
+	t.Helper()
+
+	f := &tlsFixture{dir: t.TempDir(), serverCA: newTestCA(t, "server CA"), clientCA: newTestCA(t, "client CA")}
+	f.server = newTestCert(t, f.serverCA, x509.Certificate{
+		Subject:     pkix.Name{CommonName: "upstream.internal"},
+		DNSNames:    []string{"upstream.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	f.serverCA.write(t, filepath.Join(f.dir, "ca.pem"), "")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"client":"` + r.TLS.PeerCertificates[0].Subject.CommonName + `"}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{f.server.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    f.clientCA.pool(),
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	var err error
+	f.url, err = url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return f
+}
+
+// writeClientCert writes a client certificate signed by ca and returns the
+// certificate and key file names.
+func (f *tlsFixture) writeClientCert(t *testing.T, ca *testCert, name string) (string, string) {
+	t.Helper()
+
+	cert := newTestCert(t, ca, x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	certFile, keyFile := filepath.Join(f.dir, "client.pem"), filepath.Join(f.dir, "client-key.pem")
+	cert.write(t, certFile, keyFile)
+
+	return certFile, keyFile
+}
+
+func (f *tlsFixture) do(t *testing.T, opts TLSOptions) ([]byte, error) {
+	t.Helper()
+
+	client, err := NewClient(ClientOptions{BaseURL: f.url, TLS: opts})
+	require.NoError(t, err)
+
+	return client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+}
+
+func TestClient_TLS(t *testing.T) {
+	f := newTLSFixture(t)
+	certFile, keyFile := f.writeClientCert(t, f.clientCA, "svc")
+	caFile := filepath.Join(f.dir, "ca.pem")
+
+	base := TLSOptions{CertFile: certFile, KeyFile: keyFile, CAFiles: []string{caFile}, ServerName: "upstream.internal"}
+
+	body, err := f.do(t, base)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"client":"svc"}`, string(body))
+
+	t.Run("without client certificate", func(t *testing.T) {
+		opts := base
+		opts.CertFile, opts.KeyFile = "", ""
+
+		_, err := f.do(t, opts)
+		assert.Error(t, err)
+	})
+
+	t.Run("without server name override", func(t *testing.T) {
+		opts := base
+		opts.ServerName = ""
+
+		_, err := f.do(t, opts)
+		assert.ErrorContains(t, err, "doesn't contain any IP SANs")
+	})
+
+	t.Run("unknown CA", func(t *testing.T) {
+		other := filepath.Join(f.dir, "other-ca.pem")
+		newTestCA(t, "other CA").write(t, other, "")
+
+		opts := base
+		opts.CAFiles = []string{other}
+
+		_, err := f.do(t, opts)
+		assert.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		opts := base
+		opts.PinnedSHA256 = []string{newTestCA(t, "unrelated").pin(), f.serverCA.pin()}
+
+		_, err := f.do(t, opts)
+		require.NoError(t, err)
+
+		opts.PinnedSHA256 = []string{newTestCA(t, "unrelated").pin()}
+
+		_, err = f.do(t, opts)
+		assert.ErrorIs(t, err, ErrCertificatePin)
+	})
+
+	t.Run("pinned without verification", func(t *testing.T) {
+		do := func(pins ...string) error {
+			client, err := NewClient(ClientOptions{
+				BaseURL:            f.url,
+				InsecureSkipVerify: true,
+				TLS:                TLSOptions{CertFile: certFile, KeyFile: keyFile, PinnedSHA256: pins},
+			})
+			require.NoError(t, err)
+
+			_, err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+
+			return err
+		}
+
+		require.NoError(t, do(f.server.pin()))
+		assert.ErrorIs(t, do(newTestCA(t, "unrelated").pin()), ErrCertificatePin, "the pins still apply")
+	})
+
+	t.Run("minimum version", func(t *testing.T) {
+		opts := base
+		opts.MinVersion = tls.VersionTLS13
+
+		_, err := f.do(t, opts)
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_TLS_reload(t *testing.T) {
+	f := newTLSFixture(t)
+	certFile, keyFile := f.writeClientCert(t, newTestCA(t, "old client CA"), "old")
+
+	client, err := NewClient(ClientOptions{BaseURL: f.url, TLS: TLSOptions{
+		CertFile: certFile, KeyFile: keyFile, CAFiles: []string{filepath.Join(f.dir, "ca.pem")},
+		ServerName: "upstream.internal", ReloadInterval: time.Nanosecond,
+	}})
+	require.NoError(t, err)
+
+	_, err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.Error(t, err, "the server does not trust the old client CA")
+
+	// Rotate the client certificate on disk.
+	f.writeClientCert(t, f.clientCA, "new")
+
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	body, err := client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"client":"new"}`, string(body))
+}
+
+func TestNewTransport_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TLSOptions
+		want string
+	}{
+		{"key without certificate", TLSOptions{KeyFile: "key.pem"}, "must be given together"},
+		{"missing certificate", TLSOptions{CertFile: "missing.pem", KeyFile: "missing.pem"}, "failed to load TLS client certificate"},
+		{"missing CA bundle", TLSOptions{CAFiles: []string{"missing.pem"}}, "failed to read CA bundle"},
+		{"bad pin", TLSOptions{PinnedSHA256: []string{"abc"}}, "invalid certificate pin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTransport(ClientOptions{TLS: tt.opts}, nil)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+
+	_, err := newTransport(ClientOptions{TLS: TLSOptions{CAFiles: []string{empty}}}, nil)
+	assert.ErrorContains(t, err, "no certificates found")
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	v, err := ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	_, err = ParseTLSVersion("1.4")
+	assert.Error(t, err)
+}
@@ -1,11 +1,12 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
-
-	"github.com/spf13/viper"
-	log "go.uber.org/zap"
+	"time"
 )
 
 //nolint:revive // These are env variables
@@ -14,30 +15,341 @@ const (
 	HOST                        = "HOST"
 	HTTP_PORT                   = "HTTP_PORT"
 	GRPC_PORT                   = "GRPC_PORT"
+	SHUTDOWN_TIMEOUT            = "SHUTDOWN_TIMEOUT"
+	GRPC_REFLECTION             = "GRPC_REFLECTION"
+	CORS_ALLOWED_ORIGINS        = "CORS_ALLOWED_ORIGINS"
 	SOCKS5_PROXY                = "SOCKS5_PROXY"
+	UPSTREAM_BASE_URL           = "UPSTREAM_BASE_URL"
+	UPSTREAM_HTTP_PROXY         = "UPSTREAM_HTTP_PROXY"
+	UPSTREAM_NO_PROXY           = "UPSTREAM_NO_PROXY"
+	UPSTREAM_TLS_CERT_FILE      = "UPSTREAM_TLS_CERT_FILE"
+	UPSTREAM_TLS_KEY_FILE       = "UPSTREAM_TLS_KEY_FILE"
+	UPSTREAM_TLS_CA_FILES       = "UPSTREAM_TLS_CA_FILES"
+	UPSTREAM_TLS_MIN_VERSION    = "UPSTREAM_TLS_MIN_VERSION"
+	UPSTREAM_TLS_SERVER_NAME    = "UPSTREAM_TLS_SERVER_NAME"
+	UPSTREAM_TLS_PINS           = "UPSTREAM_TLS_PINS"
+	UPSTREAM_RATE_LIMIT         = "UPSTREAM_RATE_LIMIT"
+	UPSTREAM_RATE_BURST         = "UPSTREAM_RATE_BURST"
+	UPSTREAM_MAX_IN_FLIGHT      = "UPSTREAM_MAX_IN_FLIGHT"
+	UPSTREAM_CACHE_ENTRIES      = "UPSTREAM_CACHE_ENTRIES"
+	UPSTREAM_TIMEOUT            = "UPSTREAM_TIMEOUT"
+	UPSTREAM_ATTEMPT_TIMEOUT    = "UPSTREAM_ATTEMPT_TIMEOUT"
+	UPSTREAM_HEDGING            = "UPSTREAM_HEDGING"
+	UPSTREAM_HEDGE_MIN_DELAY    = "UPSTREAM_HEDGE_MIN_DELAY"
 	LOG_LEVEL                   = "LOG_LEVEL"
 	ENV                         = "ENV"
 	DB_ADDRESS                  = "DB_ADDRESS"
+	DB_PORT                     = "DB_PORT"
 	DB_NAME                     = "DB_NAME"
 	DB_USER                     = "DB_USER"
 	DB_PW                       = "DB_PW"
+	DB_SSLMODE                  = "DB_SSLMODE"
+	DB_SSLROOTCERT              = "DB_SSLROOTCERT"
+	DB_APPLICATION_NAME         = "DB_APPLICATION_NAME"
+	DB_MAX_OPEN_CONNS           = "DB_MAX_OPEN_CONNS"
+	DB_MAX_IDLE_CONNS           = "DB_MAX_IDLE_CONNS"
+	DB_CONN_MAX_LIFETIME        = "DB_CONN_MAX_LIFETIME"
+	DB_CONN_MAX_IDLE_TIME       = "DB_CONN_MAX_IDLE_TIME"
+	DB_CONNECT_TIMEOUT          = "DB_CONNECT_TIMEOUT"
+	DB_REPLICAS                 = "DB_REPLICAS"
+	DB_REPLICA_BALANCER         = "DB_REPLICA_BALANCER"
+	DB_REPLICA_CHECK_INTERVAL   = "DB_REPLICA_CHECK_INTERVAL"
+	DB_SLOW_QUERY_THRESHOLD     = "DB_SLOW_QUERY_THRESHOLD"
+	MIGRATE_ON_START            = "MIGRATE_ON_START"
+	MIGRATE_LOCK_TIMEOUT        = "MIGRATE_LOCK_TIMEOUT"
 	OTEL_EXPORTER_OTLP_ENDPOINT = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OTEL_TRACES_SAMPLER_RATIO   = "OTEL_TRACES_SAMPLER_RATIO"
+	PASSWORD_ARGON2_TIME        = "PASSWORD_ARGON2_TIME"
+	PASSWORD_ARGON2_MEMORY      = "PASSWORD_ARGON2_MEMORY"
+	PASSWORD_ARGON2_THREADS     = "PASSWORD_ARGON2_THREADS"
+	PASSWORD_MIN_LENGTH         = "PASSWORD_MIN_LENGTH"
+	PASSWORD_MAX_LENGTH         = "PASSWORD_MAX_LENGTH"
+	PASSWORD_BREACHED_LIST      = "PASSWORD_BREACHED_LIST"
 )
 
-func Get(key string) string {
-	viper.AutomaticEnv()
+// secretResolveTimeout bounds the time Load spends resolving secret references.
+const secretResolveTimeout = 10 * time.Second
+
+// Config is the typed application configuration. It is loaded once at
+// startup by Load and passed to the components that need it.
+//
+// Every leaf field carries a `config` tag naming its key, an optional
+// `default` tag and an optional `validate:"required"` tag. Fields tagged
+// `secret:"true"` are never echoed back in error messages or `config show`.
+//
+// Any value may instead be a reference such as file:///run/secrets/db_pw or
+// env://OTHER_VAR, resolved through the registered SecretProviders.
+type Config struct {
+	App        App
+	Server     Server
+	DB         Database
+	Tracing    Tracing
+	HTTPClient HTTPClient
+	Password   Password
+
+	settings []Setting
+}
+
+// App holds settings describing the running application.
+type App struct {
+	Name     string `config:"APP_NAME" default:"go-template" validate:"required"`
+	Env      string `config:"ENV" default:"local" validate:"required"`
+	LogLevel string `config:"LOG_LEVEL" default:"info"`
+}
+
+// Server holds the listener settings shared by the HTTP and gRPC servers.
+type Server struct {
+	Host            string        `config:"HOST" default:"localhost"`
+	HTTPPort        int           `config:"HTTP_PORT" default:"8081"`
+	GRPCPort        int           `config:"GRPC_PORT" default:"8082"`
+	ShutdownTimeout time.Duration `config:"SHUTDOWN_TIMEOUT" default:"10s"`
+	GRPCReflection  bool          `config:"GRPC_REFLECTION" default:"true"`
+	CORSOrigins     []string      `config:"CORS_ALLOWED_ORIGINS" default:"*"`
+}
+
+// Database holds the PostgreSQL connection and pool settings.
+type Database struct {
+	Address         string        `config:"DB_ADDRESS" validate:"required"`
+	Port            int           `config:"DB_PORT" default:"5432"`
+	Name            string        `config:"DB_NAME" validate:"required"`
+	User            string        `config:"DB_USER" validate:"required"`
+	Password        Secret        `config:"DB_PW" validate:"required" secret:"true"`
+	SSLMode         string        `config:"DB_SSLMODE" default:"disable"`
+	SSLRootCert     string        `config:"DB_SSLROOTCERT"`
+	ApplicationName string        `config:"DB_APPLICATION_NAME" default:"go-template"`
+	MaxOpenConns    int           `config:"DB_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `config:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime time.Duration `config:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `config:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+	ConnectTimeout  time.Duration `config:"DB_CONNECT_TIMEOUT" default:"30s"`
+
+	// SlowQueryThreshold is the duration above which a statement is logged;
+	// zero disables the log.
+	SlowQueryThreshold time.Duration `config:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
 
-	key = strings.ToUpper(key)
+	// Replicas lists read replicas as DSNs, or as host[:port] sharing every
+	// other setting with the primary. They may carry passwords, so the list
+	// is treated as a secret.
+	Replicas             []string      `config:"DB_REPLICAS" secret:"true"`
+	ReplicaBalancer      string        `config:"DB_REPLICA_BALANCER" default:"round-robin"`
+	ReplicaCheckInterval time.Duration `config:"DB_REPLICA_CHECK_INTERVAL" default:"5s"`
+
+	// MigrateOnStart makes `serve` apply pending migrations before reporting
+	// ready. MigrateLockTimeout bounds the wait while another replica migrates.
+	MigrateOnStart     bool          `config:"MIGRATE_ON_START" default:"false"`
+	MigrateLockTimeout time.Duration `config:"MIGRATE_LOCK_TIMEOUT" default:"5m"`
+}
+
+// Tracing holds the OpenTelemetry exporter settings.
+type Tracing struct {
+	OTLPEndpoint string  `config:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	SampleRatio  float64 `config:"OTEL_TRACES_SAMPLER_RATIO" default:"1"`
+}
+
+// HTTPClient holds the settings for outbound HTTP calls. The proxies may
+// carry credentials, so they are treated as secrets.
+type HTTPClient struct {
+	BaseURL     *url.URL `config:"UPSTREAM_BASE_URL" default:"https://hacker-news.firebaseio.com/v0/"`
+	Socks5Proxy string   `config:"SOCKS5_PROXY" secret:"true"`
+	HTTPProxy   string   `config:"UPSTREAM_HTTP_PROXY" secret:"true"`
+	NoProxy     string   `config:"UPSTREAM_NO_PROXY"`
+	// TLSCertFile and TLSKeyFile are the client certificate for mutual TLS.
+	// They and the CA bundles are reloaded when the files change.
+	TLSCertFile   string   `config:"UPSTREAM_TLS_CERT_FILE"`
+	TLSKeyFile    string   `config:"UPSTREAM_TLS_KEY_FILE"`
+	TLSCAFiles    []string `config:"UPSTREAM_TLS_CA_FILES"`
+	TLSMinVersion string   `config:"UPSTREAM_TLS_MIN_VERSION" default:"1.2"`
+	TLSServerName string   `config:"UPSTREAM_TLS_SERVER_NAME"`
+	// TLSPins are base64 SHA-256 hashes of pinned public keys.
+	TLSPins []string `config:"UPSTREAM_TLS_PINS"`
+	// RateLimit, in requests per second, and MaxInFlight bound the calls to
+	// the upstream; 0 means unlimited.
+	RateLimit   float64 `config:"UPSTREAM_RATE_LIMIT" default:"0"`
+	RateBurst   int     `config:"UPSTREAM_RATE_BURST" default:"1"`
+	MaxInFlight int     `config:"UPSTREAM_MAX_IN_FLIGHT" default:"0"`
+	// CacheEntries sizes the in-memory cache of upstream responses; 0
+	// disables caching.
+	CacheEntries int `config:"UPSTREAM_CACHE_ENTRIES" default:"0"`
+	// Timeout bounds each call, retries included, and AttemptTimeout each
+	// attempt; 0 leaves attempts bounded by Timeout only.
+	Timeout        time.Duration `config:"UPSTREAM_TIMEOUT" default:"10s"`
+	AttemptTimeout time.Duration `config:"UPSTREAM_ATTEMPT_TIMEOUT" default:"0s"`
+	Hedging        bool          `config:"UPSTREAM_HEDGING" default:"false"`
+	HedgeMinDelay  time.Duration `config:"UPSTREAM_HEDGE_MIN_DELAY" default:"50ms"`
+}
+
+// Password holds the password hashing cost and password policy settings.
+// Argon2Memory is in KiB.
+type Password struct {
+	Argon2Time    int    `config:"PASSWORD_ARGON2_TIME" default:"3"`
+	Argon2Memory  int    `config:"PASSWORD_ARGON2_MEMORY" default:"65536"`
+	Argon2Threads int    `config:"PASSWORD_ARGON2_THREADS" default:"2"`
+	MinLength     int    `config:"PASSWORD_MIN_LENGTH" default:"12"`
+	MaxLength     int    `config:"PASSWORD_MAX_LENGTH" default:"128"`
+	BreachedList  string `config:"PASSWORD_BREACHED_LIST"`
+}
+
+// HTTPAddr returns the host:port the HTTP server listens on.
+func (s Server) HTTPAddr() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.HTTPPort)
+}
 
-	return fmt.Sprintf("%v", viper.Get(key))
+// GRPCAddr returns the host:port the gRPC server listens on.
+func (s Server) GRPCAddr() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.GRPCPort)
 }
 
-func init() {
-	viper.SetConfigFile(".env")
-	err := viper.ReadInConfig()
-	viper.WatchConfig()
+// Load resolves every configuration key against sources, applies defaults
+// and validates the result. Sources are given from lowest to highest
+// precedence; the documented order is defaults (from the struct tags), then
+// the config file, then APP_-prefixed environment variables, then flags.
+//
+// The returned error is a *ValidationError listing every missing or invalid
+// key, so a misconfigured deployment can be fixed in one pass.
+func Load(sources ...Source) (*Config, error) {
+	return load(sources, nil)
+}
+
+// LoadWithoutDatabase is Load for commands that never connect to the
+// database: the DB_ keys are still decoded and checked, but none of them is
+// required.
+func LoadWithoutDatabase(sources ...Source) (*Config, error) {
+	return load(sources, isDatabaseKey)
+}
+
+func isDatabaseKey(key string) bool {
+	return strings.HasPrefix(key, "DB_")
+}
+
+func load(sources []Source, optional func(string) bool) (*Config, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	cfg := &Config{}
+
+	d := decode(ctx, cfg, layered(sources), secrets, optional)
+	problems := append(d.problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	cfg.settings = d.settings
+
+	return cfg, nil
+}
+
+// Settings reports every key with its effective value and the source it came
+// from. Secret values are masked.
+func (c *Config) Settings() []Setting {
+	return slices.Clone(c.settings)
+}
+
+// validate performs the checks that span more than a single field.
+func (c *Config) validate() []string {
+	var problems []string
+
+	ports := []struct {
+		key  string
+		port int
+	}{
+		{HTTP_PORT, c.Server.HTTPPort},
+		{GRPC_PORT, c.Server.GRPCPort},
+		{DB_PORT, c.DB.Port},
+	}
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s: port %d out of range 1-65535", p.key, p.port))
+		}
+	}
+
+	if c.Server.HTTPPort == c.Server.GRPCPort {
+		problems = append(problems, fmt.Sprintf("%s and %s must differ", HTTP_PORT, GRPC_PORT))
+	}
+
+	switch c.DB.SSLMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unknown mode %q, want disable, require, verify-ca or verify-full", DB_SSLMODE, c.DB.SSLMode))
+	}
+
+	if c.DB.MaxIdleConns > c.DB.MaxOpenConns && c.DB.MaxOpenConns > 0 {
+		problems = append(problems, fmt.Sprintf("%s must not exceed %s", DB_MAX_IDLE_CONNS, DB_MAX_OPEN_CONNS))
+	}
+
+	switch c.DB.ReplicaBalancer {
+	case "round-robin", "least-connections":
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unknown balancer %q, want round-robin or least-connections", DB_REPLICA_BALANCER, c.DB.ReplicaBalancer))
+	}
+
+	if c.DB.SlowQueryThreshold < 0 {
+		problems = append(problems, DB_SLOW_QUERY_THRESHOLD+" must not be negative")
+	}
+
+	if len(c.DB.Replicas) > 0 && c.DB.ReplicaCheckInterval <= 0 {
+		problems = append(problems, DB_REPLICA_CHECK_INTERVAL+" must be positive")
+	}
+
+	if (c.HTTPClient.TLSCertFile == "") != (c.HTTPClient.TLSKeyFile == "") {
+		problems = append(problems, fmt.Sprintf("%s and %s must be set together", UPSTREAM_TLS_CERT_FILE, UPSTREAM_TLS_KEY_FILE))
+	}
+
+	switch c.HTTPClient.TLSMinVersion {
+	case "1.0", "1.1", "1.2", "1.3":
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unknown version %q, want 1.0, 1.1, 1.2 or 1.3", UPSTREAM_TLS_MIN_VERSION, c.HTTPClient.TLSMinVersion))
+	}
+
+	if c.HTTPClient.RateLimit < 0 || c.HTTPClient.RateBurst < 0 || c.HTTPClient.MaxInFlight < 0 {
+		problems = append(problems, fmt.Sprintf("%s, %s and %s must not be negative", UPSTREAM_RATE_LIMIT, UPSTREAM_RATE_BURST, UPSTREAM_MAX_IN_FLIGHT))
+	}
+
+	if c.HTTPClient.CacheEntries < 0 {
+		problems = append(problems, UPSTREAM_CACHE_ENTRIES+" must not be negative")
+	}
 
-	if err != nil {
-		log.S().Error("Fatal error config file ", err)
+	if c.HTTPClient.Timeout <= 0 {
+		problems = append(problems, UPSTREAM_TIMEOUT+" must be positive")
 	}
+
+	if c.HTTPClient.AttemptTimeout < 0 || c.HTTPClient.HedgeMinDelay < 0 {
+		problems = append(problems, fmt.Sprintf("%s and %s must not be negative", UPSTREAM_ATTEMPT_TIMEOUT, UPSTREAM_HEDGE_MIN_DELAY))
+	}
+
+	switch strings.ToLower(c.App.LogLevel) {
+	case "debug", "info", "warn", "error", "dpanic", "panic", "fatal":
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unknown level %q", LOG_LEVEL, c.App.LogLevel))
+	}
+
+	if c.App.Env != "local" && c.Tracing.OTLPEndpoint == "" {
+		problems = append(problems, OTEL_EXPORTER_OTLP_ENDPOINT+" is required when "+ENV+" is not local")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("%s: ratio %v out of range 0-1", OTEL_TRACES_SAMPLER_RATIO, c.Tracing.SampleRatio))
+	}
+
+	if c.Password.Argon2Time < 1 {
+		problems = append(problems, PASSWORD_ARGON2_TIME+" must be at least 1")
+	}
+
+	if c.Password.Argon2Threads < 1 || c.Password.Argon2Threads > 255 {
+		problems = append(problems, fmt.Sprintf("%s: %d out of range 1-255", PASSWORD_ARGON2_THREADS, c.Password.Argon2Threads))
+	}
+
+	if c.Password.Argon2Memory < 8*c.Password.Argon2Threads {
+		problems = append(problems, fmt.Sprintf("%s must be at least 8 KiB per thread", PASSWORD_ARGON2_MEMORY))
+	}
+
+	if c.Password.MinLength < 1 {
+		problems = append(problems, PASSWORD_MIN_LENGTH+" must be at least 1")
+	}
+
+	if c.Password.MaxLength < c.Password.MinLength {
+		problems = append(problems, fmt.Sprintf("%s must not be less than %s", PASSWORD_MAX_LENGTH, PASSWORD_MIN_LENGTH))
+	}
+
+	return problems
 }
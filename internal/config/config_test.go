@@ -0,0 +1,241 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mapLookup(values map[string]string) lookupFunc {
+	return func(key string) (string, string, bool) {
+		v, ok := values[key]
+
+		return v, "test", ok
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string]string
+		problems []string
+		check    func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults applied",
+			values: map[string]string{
+				DB_ADDRESS: "db", DB_NAME: "app", DB_USER: "app", DB_PW: "secret",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "go-template", cfg.App.Name)
+				assert.Equal(t, 8081, cfg.Server.HTTPPort)
+				assert.Equal(t, 10*time.Second, cfg.Server.ShutdownTimeout)
+				assert.True(t, cfg.Server.GRPCReflection)
+				assert.InDelta(t, 1.0, cfg.Tracing.SampleRatio, 0)
+				assert.Equal(t, "hacker-news.firebaseio.com", cfg.HTTPClient.BaseURL.Host)
+			},
+		},
+		{
+			name: "typed values parsed",
+			values: map[string]string{
+				DB_ADDRESS: "db", DB_NAME: "app", DB_USER: "app", DB_PW: "secret",
+				HTTP_PORT: "9000", SHUTDOWN_TIMEOUT: "3s", GRPC_REFLECTION: "false",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9000, cfg.Server.HTTPPort)
+				assert.Equal(t, 3*time.Second, cfg.Server.ShutdownTimeout)
+				assert.False(t, cfg.Server.GRPCReflection)
+			},
+		},
+		{
+			name: "every problem reported",
+			values: map[string]string{
+				DB_PW:             "not-echoed",
+				HTTP_PORT:         "http",
+				SHUTDOWN_TIMEOUT:  "soon",
+				UPSTREAM_BASE_URL: "relative/path",
+			},
+			problems: []string{
+				`HTTP_PORT: invalid value "http": must be an integer`,
+				`SHUTDOWN_TIMEOUT: invalid value "soon": time: invalid duration "soon"`,
+				"DB_ADDRESS is required",
+				"DB_NAME is required",
+				"DB_USER is required",
+				`UPSTREAM_BASE_URL: invalid value "relative/path": must be an absolute URL`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			d := decode(context.Background(), cfg, mapLookup(tt.values), nil, nil)
+
+			assert.Equal(t, tt.problems, d.problems)
+
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+DB_ADDRESS: db
+DB_NAME: app
+DB_USER: app
+DB_PW: from-file
+HOST: file-host
+HTTP_PORT: 7000
+GRPC_PORT: 7001
+`), 0o600))
+
+	file, err := NewFileSource(path)
+	require.NoError(t, err)
+
+	t.Setenv(EnvPrefix+HTTP_PORT, "7100")
+	t.Setenv(EnvPrefix+GRPC_PORT, "7101")
+	t.Setenv(GRPC_PORT, "7999") // unprefixed variables are ignored
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("grpc-port", 0, "")
+	flags.String("host", "", "")
+	require.NoError(t, flags.Parse([]string{"--grpc-port", "7201"}))
+
+	cfg, err := Load(file, EnvSource{}, NewFlagSource(flags))
+	require.NoError(t, err)
+
+	assert.Equal(t, "file-host", cfg.Server.Host)
+	assert.Equal(t, 7100, cfg.Server.HTTPPort)
+	assert.Equal(t, 7201, cfg.Server.GRPCPort)
+
+	sources := map[string]Setting{}
+	for _, s := range cfg.Settings() {
+		s.raw = ""
+		sources[s.Key] = s
+	}
+
+	assert.Equal(t, Setting{Key: HOST, Value: "file-host", Source: "file:" + path}, sources[HOST])
+	assert.Equal(t, Setting{Key: HTTP_PORT, Value: "7100", Source: "env"}, sources[HTTP_PORT])
+	assert.Equal(t, Setting{Key: GRPC_PORT, Value: "7201", Source: "flag"}, sources[GRPC_PORT])
+	assert.Equal(t, Setting{Key: APP_NAME, Value: "go-template", Source: "default"}, sources[APP_NAME])
+	assert.Equal(t, Setting{Key: DB_PW, Value: "******", Source: "file:" + path}, sources[DB_PW])
+	assert.Equal(t, Setting{Key: SOCKS5_PROXY, Source: "unset"}, sources[SOCKS5_PROXY])
+}
+
+func TestLoad_emptyValueFallsThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+DB_ADDRESS: db
+DB_NAME: app
+DB_USER: app
+DB_PW: secret
+`), 0o600))
+
+	file, err := NewFileSource(path)
+	require.NoError(t, err)
+
+	t.Setenv(EnvPrefix+DB_ADDRESS, "")
+
+	cfg, err := Load(file, EnvSource{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "db", cfg.DB.Address)
+}
+
+func TestLoadWithoutDatabase(t *testing.T) {
+	for _, key := range []string{DB_ADDRESS, DB_NAME, DB_USER, DB_PW} {
+		t.Setenv(EnvPrefix+key, "")
+	}
+
+	_, err := Load(EnvSource{})
+	require.ErrorContains(t, err, DB_ADDRESS+" is required")
+
+	cfg, err := LoadWithoutDatabase(EnvSource{})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.DB.Address)
+
+	// The database settings that are given are still checked.
+	t.Setenv(EnvPrefix+DB_PORT, "0")
+
+	_, err = LoadWithoutDatabase(EnvSource{})
+	require.ErrorContains(t, err, DB_PORT)
+}
+
+func TestFileSource_list(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+CORS_ALLOWED_ORIGINS: [https://a.example, https://b.example]
+DB_REPLICAS:
+  - replica-1:5432
+  - replica-2:5432
+`), 0o600))
+
+	file, err := NewFileSource(path)
+	require.NoError(t, err)
+
+	origins, ok := file.Lookup(CORS_ALLOWED_ORIGINS)
+	require.True(t, ok)
+	assert.Equal(t, "https://a.example,https://b.example", origins)
+
+	cfg := &Config{}
+	decode(context.Background(), cfg, layered([]Source{file}), nil, nil)
+
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, []string{"replica-1:5432", "replica-2:5432"}, cfg.DB.Replicas)
+}
+
+func TestConfig_validate(t *testing.T) {
+	cfg := &Config{
+		App:    App{Name: "app", Env: "production", LogLevel: "loud"},
+		Server: Server{HTTPPort: 8080, GRPCPort: 8080},
+		DB: Database{
+			Port: 5432, SSLMode: "prefer", MaxOpenConns: 5, MaxIdleConns: 10,
+			Replicas: []string{"replica"}, ReplicaBalancer: "random",
+		},
+		Tracing: Tracing{SampleRatio: 2},
+		HTTPClient: HTTPClient{
+			TLSKeyFile: "client.key", TLSMinVersion: "1.4",
+			MaxInFlight: -1, CacheEntries: -1, AttemptTimeout: -time.Second,
+		},
+		Password: Password{
+			Argon2Time: 1, Argon2Memory: 64 * 1024, Argon2Threads: 1,
+			MinLength: 12, MaxLength: 8,
+		},
+	}
+
+	assert.Equal(t, []string{
+		"HTTP_PORT and GRPC_PORT must differ",
+		`DB_SSLMODE: unknown mode "prefer", want disable, require, verify-ca or verify-full`,
+		"DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS",
+		`DB_REPLICA_BALANCER: unknown balancer "random", want round-robin or least-connections`,
+		"DB_REPLICA_CHECK_INTERVAL must be positive",
+		"UPSTREAM_TLS_CERT_FILE and UPSTREAM_TLS_KEY_FILE must be set together",
+		`UPSTREAM_TLS_MIN_VERSION: unknown version "1.4", want 1.0, 1.1, 1.2 or 1.3`,
+		"UPSTREAM_RATE_LIMIT, UPSTREAM_RATE_BURST and UPSTREAM_MAX_IN_FLIGHT must not be negative",
+		"UPSTREAM_CACHE_ENTRIES must not be negative",
+		"UPSTREAM_TIMEOUT must be positive",
+		"UPSTREAM_ATTEMPT_TIMEOUT and UPSTREAM_HEDGE_MIN_DELAY must not be negative",
+		`LOG_LEVEL: unknown level "loud"`,
+		"OTEL_EXPORTER_OTLP_ENDPOINT is required when ENV is not local",
+		"OTEL_TRACES_SAMPLER_RATIO: ratio 2 out of range 0-1",
+		"PASSWORD_MAX_LENGTH must not be less than PASSWORD_MIN_LENGTH",
+	}, cfg.validate())
+}
+
+func TestValidationError(t *testing.T) {
+	var err error = &ValidationError{Problems: []string{"A is required", "B is required"}}
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, "invalid configuration:\n  - A is required\n  - B is required", err.Error())
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError reports every configuration key that is missing or holds
+// a value that cannot be parsed.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// lookupFunc returns the raw value of a key, the name of the source that
+// supplied it and whether it was set at all.
+type lookupFunc func(key string) (value, source string, found bool)
+
+// Setting is one resolved configuration key, as reported by `config show`.
+// Secret values are masked.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+
+	raw string // unmasked value, used to detect changes on reload
+}
+
+const (
+	sourceDefault = "default"
+	sourceUnset   = "unset"
+	maskedValue   = "******"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlType      = reflect.TypeOf(&url.URL{})
+)
+
+// decoder fills tagged struct fields from a lookup and collects a problem
+// for every bad key along with the source of every value.
+type decoder struct {
+	ctx      context.Context
+	lookup   lookupFunc
+	secrets  *SecretResolver
+	optional func(key string) bool
+	problems []string
+	settings []Setting
+}
+
+// decode walks the tagged fields of out, which must be a pointer to a struct,
+// and fills them from lookup. Values that are references to a scheme known
+// to secrets are replaced by the secret they point to. Keys accepted by
+// optional are exempt from validate:"required". secrets and optional may be
+// nil.
+func decode(ctx context.Context, out any, lookup lookupFunc, secrets *SecretResolver, optional func(string) bool) *decoder {
+	d := &decoder{ctx: ctx, lookup: lookup, secrets: secrets, optional: optional}
+	d.decodeStruct(reflect.ValueOf(out).Elem())
+
+	return d
+}
+
+func (d *decoder) decodeStruct(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		key, ok := field.Tag.Lookup("config")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				d.decodeStruct(fv)
+			}
+
+			continue
+		}
+
+		secret := field.Tag.Get("secret") == "true"
+
+		raw, source, found := d.lookup(key)
+		if !found || raw == "" {
+			raw, source = field.Tag.Get("default"), sourceDefault
+		}
+
+		if d.secrets != nil && raw != "" {
+			value, isRef, err := d.secrets.Resolve(d.ctx, raw)
+			if err != nil {
+				d.settings = append(d.settings, Setting{Key: key, Value: maskedValue, Source: source + " (" + raw + ")"})
+				d.problems = append(d.problems, fmt.Sprintf("%s: %v", key, err))
+
+				continue
+			}
+
+			if isRef {
+				// Whatever a reference points to is treated as a secret.
+				secret = true
+				source += " (" + raw + ")"
+				raw = value
+			}
+		}
+
+		if raw == "" {
+			d.settings = append(d.settings, Setting{Key: key, Source: sourceUnset})
+
+			if field.Tag.Get("validate") == "required" && (d.optional == nil || !d.optional(key)) {
+				d.problems = append(d.problems, key+" is required")
+			}
+
+			continue
+		}
+
+		shown := raw
+		if secret {
+			shown = maskedValue
+		}
+
+		d.settings = append(d.settings, Setting{Key: key, Value: shown, Source: source, raw: raw})
+
+		if err := setField(fv, raw); err != nil {
+			if secret {
+				d.problems = append(d.problems, fmt.Sprintf("%s: invalid value: %v", key, err))
+			} else {
+				d.problems = append(d.problems, fmt.Sprintf("%s: invalid value %q: %v", key, raw, err))
+			}
+		}
+	}
+}
+
+// setField parses raw into fv according to the field's type.
+func setField(fv reflect.Value, raw string) error {
+	switch fv.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+
+		return nil
+	case urlType:
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute URL")
+		}
+
+		fv.Set(reflect.ValueOf(u))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", fv.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+
+	"go-template/pkg/logger"
+	"go-template/pkg/metrics"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// LoadFunc builds a fresh Config from all sources. It is called once at
+// startup and again on every reload.
+type LoadFunc func() (*Config, error)
+
+// ChangeFunc is called after a reload changed one of the keys it subscribed
+// to. old is the configuration before the reload, new the one after.
+type ChangeFunc func(old, new *Config)
+
+var reloadCounter = metrics.NewCounterVec("config_reloads_total", []string{"result"},
+	"Configuration reloads by result (applied, unchanged, rejected).")
+
+type subscription struct {
+	id   int
+	keys []string
+	fn   ChangeFunc
+}
+
+// Manager holds the live configuration and notifies subscribers when a
+// reload changes it. A reload that fails validation leaves the current
+// configuration untouched.
+type Manager struct {
+	load LoadFunc
+
+	reloadMu sync.Mutex // serializes reloads so subscribers see ordered changes
+
+	mu      sync.RWMutex
+	current *Config
+	subs    []subscription
+	nextID  int
+}
+
+// NewManager creates a Manager that loads configuration with load.
+func NewManager(load LoadFunc) *Manager {
+	return &Manager{load: load}
+}
+
+// Current returns the live configuration, or nil before the first load.
+func (m *Manager) Current() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.current
+}
+
+// OnChange registers fn to run after a reload changes any of keys. With no
+// keys fn runs on every change. The returned function unsubscribes.
+func (m *Manager) OnChange(keys []string, fn ChangeFunc) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextID
+	m.nextID++
+	m.subs = append(m.subs, subscription{id: id, keys: keys, fn: fn})
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.subs = slices.DeleteFunc(m.subs, func(s subscription) bool { return s.id == id })
+	}
+}
+
+// Reload loads and validates a new configuration and, if it is valid,
+// swaps it in and notifies the subscribers of the keys that changed.
+func (m *Manager) Reload() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	next, err := m.load()
+	if err != nil {
+		if m.Current() != nil {
+			reloadCounter.WithLabelValues("rejected").Inc()
+			logger.Error("Rejected configuration reload, keeping previous configuration", zap.Error(err))
+		}
+
+		return err
+	}
+
+	m.mu.Lock()
+	prev := m.current
+	m.current = next
+	subs := slices.Clone(m.subs)
+	m.mu.Unlock()
+
+	// The first load has nothing to compare against.
+	if prev == nil {
+		return nil
+	}
+
+	changed := changedKeys(prev, next)
+	if len(changed) == 0 {
+		reloadCounter.WithLabelValues("unchanged").Inc()
+
+		return nil
+	}
+
+	reloadCounter.WithLabelValues("applied").Inc()
+	logger.Info("Configuration reloaded", zap.Strings("changed", changed))
+
+	for _, s := range subs {
+		if len(s.keys) == 0 || slices.ContainsFunc(s.keys, func(k string) bool { return slices.Contains(changed, k) }) {
+			s.fn(prev, next)
+		}
+	}
+
+	return nil
+}
+
+// Watch reloads the configuration whenever the file at path changes or the
+// process receives SIGHUP, until ctx is done. path may be empty to only
+// react to SIGHUP. SIGHUP also drops cached secrets so rotated values are
+// picked up immediately.
+func (m *Manager) Watch(ctx context.Context, path string) error {
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+
+	var watcher *fsnotify.Watcher
+
+	if path != "" {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+
+		// Watch the directory rather than the file so that editors which
+		// replace the file are still noticed. Kubernetes config maps swap a
+		// symlink instead, caught by resolving the path on every event.
+		if err := w.Add(filepath.Dir(path)); err != nil {
+			w.Close()
+
+			return err
+		}
+
+		watcher, events, errs = w, w.Events, w.Errors
+	}
+
+	target := filepath.Clean(path)
+	resolved, _ := filepath.EvalSymlinks(path)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("Received SIGHUP, reloading configuration")
+				RefreshSecrets()
+				_ = m.Reload()
+			case ev := <-events:
+				current, _ := filepath.EvalSymlinks(path)
+				swapped := current != "" && current != resolved
+				resolved = current
+
+				if swapped || filepath.Clean(ev.Name) == target && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					_ = m.Reload()
+				}
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+
+					continue
+				}
+
+				// fsnotify blocks until its errors are read, so they are
+				// always drained. Lost events may have been changes.
+				logger.Warn("Error watching the config file", zap.String("path", path), zap.Error(err))
+
+				if errors.Is(err, fsnotify.ErrEventOverflow) {
+					_ = m.Reload()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// changedKeys lists the keys whose effective value differs between a and b.
+func changedKeys(a, b *Config) []string {
+	before := make(map[string]string, len(a.settings))
+	for _, s := range a.settings {
+		before[s.Key] = s.raw
+	}
+
+	var changed []string
+
+	for _, s := range b.settings {
+		if before[s.Key] != s.raw {
+			changed = append(changed, s.Key)
+		}
+	}
+
+	return changed
+}
+
+// std is the process-wide Manager used by the package-level functions.
+var std = NewManager(nil)
+
+// Init sets the loader of the process-wide Manager and performs the first load.
+func Init(load LoadFunc) (*Config, error) {
+	std.load = load
+
+	if err := std.Reload(); err != nil {
+		return nil, err
+	}
+
+	return std.Current(), nil
+}
+
+// Current returns the live process-wide configuration.
+func Current() *Config {
+	return std.Current()
+}
+
+// OnChange subscribes fn to changes of keys in the process-wide configuration.
+func OnChange(keys []string, fn ChangeFunc) func() {
+	return std.OnChange(keys, fn)
+}
+
+// Reload reloads the process-wide configuration.
+func Reload() error {
+	return std.Reload()
+}
+
+// Watch reloads the process-wide configuration on file changes and SIGHUP.
+func Watch(ctx context.Context, path string) error {
+	return std.Watch(ctx, path)
+}
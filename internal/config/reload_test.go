@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(body string) {
+		require.NoError(t, os.WriteFile(path, []byte("DB_ADDRESS: db\nDB_NAME: app\nDB_USER: app\nDB_PW: pw\n"+body), 0o600))
+	}
+
+	m := NewManager(func() (*Config, error) {
+		file, err := NewFileSource(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return Load(file)
+	})
+
+	write("LOG_LEVEL: info\n")
+	require.NoError(t, m.Reload())
+
+	var levels, origins []string
+
+	m.OnChange([]string{LOG_LEVEL}, func(old, next *Config) {
+		levels = append(levels, old.App.LogLevel+"->"+next.App.LogLevel)
+	})
+	unsubscribe := m.OnChange([]string{CORS_ALLOWED_ORIGINS}, func(_, next *Config) {
+		origins = append(origins, next.Server.CORSOrigins...)
+	})
+
+	// Only the log level subscriber is notified.
+	write("LOG_LEVEL: debug\n")
+	require.NoError(t, m.Reload())
+	assert.Equal(t, []string{"info->debug"}, levels)
+	assert.Empty(t, origins)
+
+	// An invalid file is rejected and the previous configuration is kept.
+	write("LOG_LEVEL: loud\n")
+	require.Error(t, m.Reload())
+	assert.Equal(t, "debug", m.Current().App.LogLevel)
+	assert.Equal(t, []string{"info->debug"}, levels)
+
+	write("LOG_LEVEL: debug\nCORS_ALLOWED_ORIGINS: https://a.example, https://b.example\n")
+	require.NoError(t, m.Reload())
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, origins)
+
+	// Unsubscribed functions are no longer called.
+	unsubscribe()
+	write("LOG_LEVEL: debug\nCORS_ALLOWED_ORIGINS: https://c.example\n")
+	require.NoError(t, m.Reload())
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, origins)
+}
+
+// TestManager_Watch_symlinkSwap mimics a Kubernetes config map update: the
+// config file is a symlink through ..data, which is atomically replaced.
+func TestManager_Watch_symlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeVersion := func(version, level string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, version, "config.yaml"),
+			[]byte("DB_ADDRESS: db\nDB_NAME: app\nDB_USER: app\nDB_PW: pw\nLOG_LEVEL: "+level+"\n"), 0o600))
+	}
+
+	writeVersion("..v1", "info")
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")))
+
+	path := filepath.Join(dir, "config.yaml")
+	m := NewManager(func() (*Config, error) {
+		file, err := NewFileSource(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return Load(file)
+	})
+	require.NoError(t, m.Reload())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, m.Watch(ctx, path))
+
+	writeVersion("..v2", "debug")
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	assert.Eventually(t, func() bool {
+		return m.Current().App.LogLevel == "debug"
+	}, 2*time.Second, 10*time.Millisecond)
+}
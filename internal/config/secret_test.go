@@ -0,0 +1,122 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretResolver_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_pw")
+	require.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0o600))
+
+	storeFile := filepath.Join(dir, "store.json")
+	require.NoError(t, os.WriteFile(storeFile, []byte(`{"db/password":"from-store"}`), 0o600))
+
+	t.Setenv("OTHER_VAR", "from-env")
+
+	r := NewSecretResolver(time.Minute, FileSecretProvider{}, EnvSecretProvider{}, NewLocalSecretStore("vault", storeFile))
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		isRef   bool
+		wantErr bool
+	}{
+		{name: "plain value", raw: "literal", want: "literal"},
+		{name: "unregistered scheme", raw: "https://example.com", want: "https://example.com"},
+		{name: "file reference", raw: "file://" + secretFile, want: "from-file", isRef: true},
+		{name: "env reference", raw: "env://OTHER_VAR", want: "from-env", isRef: true},
+		{name: "pluggable provider", raw: "vault://db/password", want: "from-store", isRef: true},
+		{name: "missing env variable", raw: "env://MISSING_VAR", isRef: true, wantErr: true},
+		{name: "missing store key", raw: "vault://db/other", isRef: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isRef, err := r.Resolve(context.Background(), tt.raw)
+
+			assert.Equal(t, tt.isRef, isRef)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecretResolver_cache(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "store.json")
+	rotate := func(value string) {
+		require.NoError(t, os.WriteFile(storeFile, []byte(`{"pw":"`+value+`"}`), 0o600))
+	}
+
+	now := time.Now()
+	r := NewSecretResolver(time.Minute, NewLocalSecretStore("vault", storeFile))
+	r.now = func() time.Time { return now }
+
+	resolve := func() string {
+		value, _, err := r.Resolve(context.Background(), "vault://pw")
+		require.NoError(t, err)
+
+		return value
+	}
+
+	rotate("v1")
+	assert.Equal(t, "v1", resolve())
+
+	// Cached until the TTL expires.
+	rotate("v2")
+	assert.Equal(t, "v1", resolve())
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, "v2", resolve())
+
+	// Refresh drops the cache immediately.
+	rotate("v3")
+	r.Refresh()
+	assert.Equal(t, "v3", resolve())
+}
+
+func TestLoad_secretReference(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "db_pw")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cret"), 0o600))
+
+	t.Setenv(EnvPrefix+DB_ADDRESS, "db")
+	t.Setenv(EnvPrefix+DB_NAME, "app")
+	t.Setenv(EnvPrefix+DB_USER, "app")
+	t.Setenv(EnvPrefix+DB_PW, "file://"+secretFile)
+
+	cfg, err := Load(EnvSource{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "s3cret", cfg.DB.Password.Value())
+
+	for _, s := range cfg.Settings() {
+		if s.Key == DB_PW {
+			assert.Equal(t, maskedValue, s.Value)
+			assert.Equal(t, "env (file://"+secretFile+")", s.Source)
+		}
+	}
+
+	// The secret never leaks through formatting or encoding.
+	assert.NotContains(t, fmt.Sprintf("%v %+v %#v", cfg.DB, cfg.DB, cfg.DB.Password), "s3cret")
+
+	b, err := json.Marshal(cfg.DB)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "s3cret")
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is prepended to every key when it is read from the environment,
+// so HTTP_PORT is set with APP_HTTP_PORT.
+const EnvPrefix = "APP_"
+
+// Source is one layer of configuration values. Load consults sources from
+// the last to the first, so later sources take precedence.
+type Source interface {
+	// Name identifies the source in `config show` output.
+	Name() string
+	// Lookup returns the raw value of key and whether the source sets it.
+	Lookup(key string) (string, bool)
+}
+
+// FileSource reads keys from a YAML, TOML, JSON or .env file. The format is
+// chosen from the file extension. Lists are joined with commas, the form
+// list keys take everywhere else.
+type FileSource struct {
+	path string
+	v    *viper.Viper
+}
+
+// NewFileSource reads the config file at path.
+func NewFileSource(path string) (*FileSource, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return &FileSource{path: path, v: v}, nil
+}
+
+func (s *FileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSource) Lookup(key string) (string, bool) {
+	if !s.v.IsSet(key) {
+		return "", false
+	}
+
+	if items, ok := s.v.Get(key).([]any); ok {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = fmt.Sprint(item)
+		}
+
+		return strings.Join(values, ","), true
+	}
+
+	return s.v.GetString(key), true
+}
+
+// EnvSource reads keys from the process environment under EnvPrefix.
+type EnvSource struct{}
+
+func (EnvSource) Name() string {
+	return "env"
+}
+
+func (EnvSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(EnvPrefix + key)
+}
+
+// FlagSource reads keys from command-line flags. A flag maps to the key
+// of the same name upper-cased with dashes replaced by underscores, so
+// --http-port sets HTTP_PORT. Only flags given explicitly count as set.
+type FlagSource struct {
+	flags *pflag.FlagSet
+}
+
+// NewFlagSource wraps a parsed flag set.
+func NewFlagSource(flags *pflag.FlagSet) *FlagSource {
+	return &FlagSource{flags: flags}
+}
+
+func (s *FlagSource) Name() string {
+	return "flag"
+}
+
+func (s *FlagSource) Lookup(key string) (string, bool) {
+	f := s.flags.Lookup(strings.ToLower(strings.ReplaceAll(key, "_", "-")))
+	if f == nil || !f.Changed {
+		return "", false
+	}
+
+	return f.Value.String(), true
+}
+
+// layered resolves a key against sources in reverse order and reports
+// which one supplied the value. An empty value counts as not set, so that
+// e.g. APP_DB_ADDRESS= leaves the value of the file in place.
+func layered(sources []Source) lookupFunc {
+	return func(key string) (string, string, bool) {
+		for i := len(sources) - 1; i >= 0; i-- {
+			if value, ok := sources[i].Lookup(key); ok && value != "" {
+				return value, sources[i].Name(), true
+			}
+		}
+
+		return "", "", false
+	}
+}
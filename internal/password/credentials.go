@@ -0,0 +1,84 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-template/internal/clients/db"
+	"go-template/internal/repository"
+	"go-template/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCredentials is returned by Credentials.Verify when the email is
+// unknown or the password is wrong. The two cases are not distinguished.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Credentials verifies user logins against a UserRepository. It is a
+// building block for a login endpoint and has no caller yet, as neither
+// server exposes one; its Hasher should be built with ParamsFromConfig like
+// the one of the gRPC user server.
+type Credentials struct {
+	users  repository.UserRepository
+	hasher *Hasher
+
+	// dummy is verified when the user does not exist, so that unknown
+	// emails take as long as wrong passwords.
+	dummy string
+}
+
+// NewCredentials returns a verifier for the users in users.
+func NewCredentials(users repository.UserRepository, hasher *Hasher) (*Credentials, error) {
+	dummy, err := hasher.Hash("dummy password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{users: users, hasher: hasher, dummy: dummy}, nil
+}
+
+// Verify returns the user with the given email if password is theirs. When
+// the stored hash uses outdated parameters or bcrypt, it is replaced with a
+// fresh hash; a failure to store it is logged but does not fail the login.
+func (c *Credentials) Verify(ctx context.Context, email, password string) (repository.User, error) {
+	// The stored hash may be replaced below, so it is read from the primary
+	// rather than a replica that could lag behind an earlier upgrade.
+	u, err := c.users.GetByEmail(db.WithPrimary(ctx), email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		_, _ = c.hasher.Verify(password, c.dummy)
+
+		return repository.User{}, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return repository.User{}, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	rehash, err := c.hasher.Verify(password, u.Password)
+	if errors.Is(err, ErrMismatch) {
+		return repository.User{}, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return repository.User{}, fmt.Errorf("failed to verify password of user %d: %w", u.ID, err)
+	}
+
+	if rehash {
+		c.rehash(ctx, u, password)
+	}
+
+	return u, nil
+}
+
+func (c *Credentials) rehash(ctx context.Context, u repository.User, password string) {
+	hash, err := c.hasher.Hash(password)
+	if err == nil {
+		err = c.users.UpdatePassword(ctx, u.ID, hash)
+	}
+
+	if err != nil {
+		logger.Warn("Failed to upgrade password hash", zap.Int64("user_id", u.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,148 @@
+// Package password hashes and verifies user passwords and enforces the
+// password policy.
+//
+// New hashes use argon2id in the PHC string format
+// ($argon2id$v=19$m=65536,t=3,p=2$salt$hash). bcrypt hashes written by
+// earlier versions are still accepted and reported as needing a rehash, so
+// they are upgraded on the next successful login.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-template/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned by Hasher.Verify.
+var (
+	ErrMismatch    = errors.New("password does not match")
+	ErrUnknownHash = errors.New("unknown password hash format")
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Params are the argon2id cost parameters.
+type Params struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+	// Memory is the memory size in KiB.
+	Memory uint32
+	// Threads is the degree of parallelism.
+	Threads uint8
+}
+
+// ParamsFromConfig returns the cost parameters configured in cfg.
+func ParamsFromConfig(cfg config.Password) Params {
+	return Params{
+		Time:    uint32(cfg.Argon2Time),
+		Memory:  uint32(cfg.Argon2Memory),
+		Threads: uint8(cfg.Argon2Threads),
+	}
+}
+
+// Hasher hashes passwords with argon2id using fixed cost parameters.
+type Hasher struct {
+	params Params
+}
+
+// NewHasher returns a Hasher using params for new hashes.
+func NewHasher(params Params) *Hasher {
+	return &Hasher{params: params}
+}
+
+// Hash returns the PHC-encoded argon2id hash of password with a random salt.
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Threads, keyLength)
+
+	return encode(h.params, salt, key), nil
+}
+
+// Verify checks password against encoded in constant time. It returns
+// ErrMismatch when the password is wrong. rehash is true when the password
+// matched but encoded was produced with other parameters or algorithm, in
+// which case the caller should store a fresh Hash.
+func (h *Hasher) Verify(password, encoded string) (rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return h.verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrMismatch
+			}
+
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return false, ErrUnknownHash
+}
+
+func (h *Hasher) verifyArgon2id(password, encoded string) (bool, error) {
+	params, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	got := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, ErrMismatch
+	}
+
+	return params != h.params || len(salt) != saltLength || len(key) != keyLength, nil
+}
+
+var b64 = base64.RawStdEncoding
+
+func encode(p Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads, b64.EncodeToString(salt), b64.EncodeToString(key))
+}
+
+func decode(encoded string) (p Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters %q: %w", parts[3], err)
+	}
+
+	if p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+
+	if key, err = b64.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,109 @@
+package password
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-template/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast; they are far below production cost.
+var testParams = Params{Time: 1, Memory: 64, Threads: 1}
+
+func TestHasher(t *testing.T) {
+	h := NewHasher(testParams)
+
+	hash, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+
+	other, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salts must differ")
+
+	rehash, err := h.Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.False(t, rehash)
+
+	_, err = h.Verify("battery staple", hash)
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	rehash, err = NewHasher(Params{Time: 2, Memory: 64, Threads: 1}).Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.True(t, rehash, "changed parameters must trigger a rehash")
+}
+
+func TestHasher_Verify_bcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	h := NewHasher(testParams)
+
+	rehash, err := h.Verify("correct horse", string(legacy))
+	require.NoError(t, err)
+	assert.True(t, rehash)
+
+	_, err = h.Verify("battery staple", string(legacy))
+	assert.ErrorIs(t, err, ErrMismatch)
+}
+
+func TestHasher_Verify_malformed(t *testing.T) {
+	h := NewHasher(testParams)
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"plaintext", "correct horse"},
+		{"missing parts", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA"},
+		{"wrong version", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$aGFzaA"},
+		{"zero threads", "$argon2id$v=19$m=64,t=1,p=0$c2FsdA$aGFzaA"},
+		{"bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!$aGFzaA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Verify("correct horse", tt.encoded)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrMismatch)
+		})
+	}
+}
+
+func TestCredentials_Verify(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	h := NewHasher(testParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	alice, err := users.Create(ctx, repository.User{Username: "alice", Email: "alice@example.com", Password: string(legacy)})
+	require.NoError(t, err)
+
+	c, err := NewCredentials(users, h)
+	require.NoError(t, err)
+
+	_, err = c.Verify(ctx, "bob@example.com", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = c.Verify(ctx, "alice@example.com", "battery staple")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	got, err := c.Verify(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, got.ID)
+
+	// The legacy bcrypt hash was upgraded on login.
+	stored, err := users.Get(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.Password, "$argon2id$"), stored.Password)
+
+	_, err = c.Verify(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+}
@@ -0,0 +1,116 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the format of published breach corpora, not used for hashing passwords
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"go-template/internal/config"
+)
+
+// Errors returned by Policy.Check.
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooLong  = errors.New("password is too long")
+	ErrBreached = errors.New("password appears in a list of breached passwords")
+)
+
+// Policy decides which passwords users may choose.
+type Policy struct {
+	MinLength int
+	MaxLength int
+
+	// breached holds the upper-case hex SHA-1 of every breached password.
+	breached map[string]struct{}
+}
+
+// NewPolicy returns the policy configured in cfg, loading the breached
+// password list if one is set.
+func NewPolicy(cfg config.Password) (*Policy, error) {
+	p := &Policy{MinLength: cfg.MinLength, MaxLength: cfg.MaxLength}
+
+	if cfg.BreachedList != "" {
+		breached, err := loadBreachedList(cfg.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+
+		p.breached = breached
+	}
+
+	return p, nil
+}
+
+// Check returns an error wrapping ErrTooShort, ErrTooLong or ErrBreached if
+// password violates the policy. Length is counted in characters, not bytes.
+func (p *Policy) Check(password string) error {
+	n := utf8.RuneCountInString(password)
+
+	if n < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrTooShort, p.MinLength)
+	}
+
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrTooLong, p.MaxLength)
+	}
+
+	if _, ok := p.breached[sha1Hex(password)]; ok {
+		return ErrBreached
+	}
+
+	return nil
+}
+
+// loadBreachedList reads a file with one entry per line. An entry is either
+// a plain-text password or the hex SHA-1 of one, optionally followed by
+// ":count" as in the Have I Been Pwned downloads. Empty lines and lines
+// starting with # are skipped.
+func loadBreachedList(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			breached[strings.ToUpper(hash)] = struct{}{}
+		} else {
+			breached[sha1Hex(line)] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return breached, nil
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s)) //nolint:gosec // see import
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-template/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte(
+		"# top passwords\n"+
+			"password1234\n"+
+			"\n"), 0o600))
+
+	p, err := NewPolicy(config.Password{MinLength: 8, MaxLength: 16, BreachedList: list})
+	require.NoError(t, err)
+
+	tests := []struct {
+		password string
+		wantErr  error
+	}{
+		{"correct horse", nil},
+		{"short", ErrTooShort},
+		{"ünïcödé", ErrTooShort},
+		{"ünïcödé!", nil},
+		{"this one is far too long", ErrTooLong},
+		{"password1234", ErrBreached},
+		{"Password1234", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := p.Check(tt.password)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_breachedSHA1(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte(sha1Hex("letmein12345")+":42\n"), 0o600))
+
+	p, err := NewPolicy(config.Password{MinLength: 8, BreachedList: list})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, p.Check("letmein12345"), ErrBreached)
+	assert.NoError(t, p.Check("letmein123456"))
+}
+
+func TestNewPolicy_missingList(t *testing.T) {
+	_, err := NewPolicy(config.Password{MinLength: 8, BreachedList: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+// Package readiness tracks whether the process is ready to receive traffic.
+// A process that is not ready is still alive: liveness checks keep passing
+// while readiness checks fail, so orchestrators hold traffic back without
+// restarting it.
+package readiness
+
+import (
+	"slices"
+	"sync"
+)
+
+// Gate holds the readiness of a process and notifies subscribers when it
+// changes. The zero value is not ready.
+type Gate struct {
+	mu     sync.RWMutex
+	ready  bool
+	reason string
+	subs   []func(ready bool)
+}
+
+// NewGate returns a Gate that starts out ready.
+func NewGate() *Gate {
+	return &Gate{ready: true}
+}
+
+// Set changes the readiness. reason explains why the process is not ready
+// and is ignored when ready is true.
+func (g *Gate) Set(ready bool, reason string) {
+	g.mu.Lock()
+
+	if ready {
+		reason = ""
+	}
+
+	changed := g.ready != ready
+	g.ready, g.reason = ready, reason
+	subs := slices.Clone(g.subs)
+
+	g.mu.Unlock()
+
+	if changed {
+		for _, fn := range subs {
+			fn(ready)
+		}
+	}
+}
+
+// Status reports whether the process is ready and, if not, why.
+func (g *Gate) Status() (ready bool, reason string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.ready, g.reason
+}
+
+// OnChange registers fn to run whenever the readiness flips.
+func (g *Gate) OnChange(fn func(ready bool)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.subs = append(g.subs, fn)
+}
+
+// std is the process-wide Gate used by the package-level functions.
+var std = NewGate()
+
+// Set changes the readiness of the process.
+func Set(ready bool, reason string) {
+	std.Set(ready, reason)
+}
+
+// Status reports the readiness of the process.
+func Status() (ready bool, reason string) {
+	return std.Status()
+}
+
+// OnChange registers fn to run whenever the readiness of the process flips.
+func OnChange(fn func(ready bool)) {
+	std.OnChange(fn)
+}
@@ -0,0 +1,34 @@
+package readiness
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGate(t *testing.T) {
+	g := NewGate()
+
+	ready, reason := g.Status()
+	assert.True(t, ready)
+	assert.Empty(t, reason)
+
+	var changes []bool
+
+	g.OnChange(func(ready bool) { changes = append(changes, ready) })
+
+	g.Set(false, "migrating")
+	g.Set(false, "migration failed")
+
+	ready, reason = g.Status()
+	assert.False(t, ready)
+	assert.Equal(t, "migration failed", reason)
+
+	g.Set(true, "ignored")
+
+	ready, reason = g.Status()
+	assert.True(t, ready)
+	assert.Empty(t, reason)
+
+	assert.Equal(t, []bool{false, true}, changes, "subscribers only see flips")
+}
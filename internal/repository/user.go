@@ -0,0 +1,57 @@
+// Package repository provides storage for the application's domain objects.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go-template/internal/clients/db"
+)
+
+// User is a stored user.
+type User = db.User
+
+// Errors returned by UserRepository implementations.
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already taken")
+)
+
+// DefaultListLimit is the page size used when ListOptions.Limit is not set.
+const DefaultListLimit = 50
+
+// ListOptions selects a page of users ordered by ID.
+type ListOptions struct {
+	// AfterID returns users with an ID greater than this one.
+	AfterID int64
+	// Limit is the maximum number of users returned.
+	Limit int
+}
+
+// UserRepository stores users.
+type UserRepository interface {
+	// Create stores u and returns it with its assigned ID.
+	Create(ctx context.Context, u User) (User, error)
+	// Get returns the user with the given ID.
+	Get(ctx context.Context, id int64) (User, error)
+	// GetByEmail returns the user with the given email address.
+	GetByEmail(ctx context.Context, email string) (User, error)
+	// List returns a page of users ordered by ID.
+	List(ctx context.Context, opts ListOptions) ([]User, error)
+	// Update replaces the stored fields of the user with u.ID.
+	Update(ctx context.Context, u User) (User, error)
+	// UpdatePassword replaces the password hash of the user with the given
+	// ID, leaving its other fields as they are.
+	UpdatePassword(ctx context.Context, id int64, hash string) error
+	// Delete removes the user with the given ID.
+	Delete(ctx context.Context, id int64) error
+}
+
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultListLimit
+	}
+
+	return o.Limit
+}
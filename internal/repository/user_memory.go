@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+var _ UserRepository = (*MemoryUserRepository)(nil)
+
+// MemoryUserRepository is an in-memory UserRepository for tests. It enforces
+// the same uniqueness rules as the users table.
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[int64]User
+	nextID int64
+}
+
+// NewMemoryUserRepository returns an empty in-memory repository.
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]User), nextID: 1}
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, u User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUnique(u, 0); err != nil {
+		return User{}, err
+	}
+
+	u.ID = r.nextID
+	r.nextID++
+	r.users[u.ID] = u
+
+	return u, nil
+}
+
+func (r *MemoryUserRepository) Get(_ context.Context, id int64) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(_ context.Context, email string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return User{}, ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) List(_ context.Context, opts ListOptions) ([]User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []User
+
+	for _, u := range r.users {
+		if u.ID > opts.AfterID {
+			users = append(users, u)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if limit := opts.limit(); len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
+func (r *MemoryUserRepository) Update(_ context.Context, u User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.ID]; !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	if err := r.checkUnique(u, u.ID); err != nil {
+		return User{}, err
+	}
+
+	r.users[u.ID] = u
+
+	return u, nil
+}
+
+func (r *MemoryUserRepository) UpdatePassword(_ context.Context, id int64, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	u.Password = hash
+	r.users[id] = u
+
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrUserNotFound
+	}
+
+	delete(r.users, id)
+
+	return nil
+}
+
+// checkUnique reports a conflict with another user's username or, failing
+// that, email. self is the ID of the user being updated, 0 on Create.
+func (r *MemoryUserRepository) checkUnique(u User, self int64) error {
+	for _, other := range r.users {
+		if other.ID != self && other.Username == u.Username {
+			return ErrUsernameTaken
+		}
+	}
+
+	for _, other := range r.users {
+		if other.ID != self && other.Email == u.Email {
+			return ErrEmailTaken
+		}
+	}
+
+	return nil
+}
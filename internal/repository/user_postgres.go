@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-template/internal/clients/db"
+
+	"github.com/lib/pq"
+)
+
+const sqlStateUniqueViolation = "23505"
+
+var _ UserRepository = (*PostgresUserRepository)(nil)
+
+// PostgresUserRepository stores users in the users table.
+type PostgresUserRepository struct {
+	q db.Querier
+}
+
+// NewPostgresUserRepository returns a repository running its statements on
+// q, which may be a *db.DB or a db.Tx to take part in a transaction. Reads
+// made through a *db.DB go to a replica when one is configured; see
+// db.WithPrimary.
+func NewPostgresUserRepository(q db.Querier) *PostgresUserRepository {
+	return &PostgresUserRepository{q: q}
+}
+
+func (r *PostgresUserRepository) Create(ctx context.Context, u User) (User, error) {
+	err := r.q.QueryRowContext(ctx, db.InsertUserQuery, u.Username, u.Password, u.Email).Scan(&u.ID)
+	if err != nil {
+		return User{}, mapUserError(err, "create user")
+	}
+
+	return u, nil
+}
+
+func (r *PostgresUserRepository) Get(ctx context.Context, id int64) (User, error) {
+	return r.getOne(ctx, db.GetUserQuery, id)
+}
+
+func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	return r.getOne(ctx, db.GetUserByEmailQuery, email)
+}
+
+func (r *PostgresUserRepository) getOne(ctx context.Context, query string, arg any) (User, error) {
+	var u User
+
+	err := db.Reader(ctx, r.q).QueryRowContext(ctx, query, arg).Scan(&u.ID, &u.Username, &u.Password, &u.Email)
+	if err != nil {
+		return User{}, mapUserError(err, "get user")
+	}
+
+	return u, nil
+}
+
+func (r *PostgresUserRepository) List(ctx context.Context, opts ListOptions) ([]User, error) {
+	rows, err := db.Reader(ctx, r.q).QueryContext(ctx, db.ListUsersQuery, opts.AfterID, opts.limit())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Password, &u.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *PostgresUserRepository) Update(ctx context.Context, u User) (User, error) {
+	res, err := r.q.ExecContext(ctx, db.UpdateUserQuery, u.ID, u.Username, u.Password, u.Email)
+	if err != nil {
+		return User{}, mapUserError(err, "update user")
+	}
+
+	if err := expectOneRow(res); err != nil {
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+func (r *PostgresUserRepository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	res, err := r.q.ExecContext(ctx, db.UpdateUserPasswordQuery, id, hash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return expectOneRow(res)
+}
+
+func (r *PostgresUserRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.q.ExecContext(ctx, db.DeleteUserQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return expectOneRow(res)
+}
+
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// mapUserError translates driver errors into the repository's domain errors.
+func mapUserError(err error, op string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == sqlStateUniqueViolation {
+		switch pqErr.Constraint {
+		case db.UsersUsernameKey:
+			return ErrUsernameTaken
+		case db.UsersEmailKey:
+			return ErrEmailTaken
+		}
+	}
+
+	return fmt.Errorf("failed to %s: %w", op, err)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go-template/internal/clients/db"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+
+	alice, err := repo.Create(ctx, User{Username: "alice", Password: "hash", Email: "alice@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), alice.ID)
+
+	bob, err := repo.Create(ctx, User{Username: "bob", Password: "hash", Email: "bob@example.com"})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, User{Username: "alice", Email: "other@example.com"})
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	_, err = repo.Create(ctx, User{Username: "carol", Email: "bob@example.com"})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	// The caller's ID is ignored on Create, so it cannot bypass the checks.
+	_, err = repo.Create(ctx, User{ID: bob.ID, Username: "carol", Email: "bob@example.com"})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	// A username conflict is reported first, whichever user it is with.
+	_, err = repo.Create(ctx, User{Username: "bob", Email: "alice@example.com"})
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	got, err := repo.GetByEmail(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, bob, got)
+
+	page, err := repo.List(ctx, ListOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []User{alice}, page)
+
+	page, err = repo.List(ctx, ListOptions{AfterID: alice.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []User{bob}, page)
+
+	bob.Email = "alice@example.com"
+	_, err = repo.Update(ctx, bob)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	bob.Email = "robert@example.com"
+	_, err = repo.Update(ctx, bob)
+	require.NoError(t, err)
+
+	got, err = repo.Get(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "robert@example.com", got.Email)
+
+	require.NoError(t, repo.UpdatePassword(ctx, bob.ID, "new hash"))
+
+	got, err = repo.Get(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", got.Password)
+	assert.Equal(t, "robert@example.com", got.Email)
+
+	require.NoError(t, repo.Delete(ctx, bob.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, bob.ID), ErrUserNotFound)
+
+	_, err = repo.Get(ctx, bob.ID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = repo.Update(ctx, User{ID: 42})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, repo.UpdatePassword(ctx, 42, "hash"), ErrUserNotFound)
+}
+
+func TestMapUserError(t *testing.T) {
+	errOther := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "no rows", err: sql.ErrNoRows, want: ErrUserNotFound},
+		{name: "username taken", err: &pq.Error{Code: "23505", Constraint: db.UsersUsernameKey}, want: ErrUsernameTaken},
+		{name: "email taken", err: &pq.Error{Code: "23505", Constraint: db.UsersEmailKey}, want: ErrEmailTaken},
+		{name: "other error", err: errOther, want: errOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, mapUserError(tt.err, "create user"), tt.want)
+		})
+	}
+}
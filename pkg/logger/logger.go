@@ -21,19 +21,24 @@ func DefaultConfig() Config {
 	}
 }
 
-var zapLog *zap.Logger
+var (
+	zapLog *zap.Logger
+	level  zap.AtomicLevel
+)
 
 // Initialize sets up the logger with the given configuration.
 func Initialize(cfg Config) error {
-	var level zapcore.Level
+	var lvl zapcore.Level
 
-	err := level.UnmarshalText([]byte(cfg.Level))
+	err := lvl.UnmarshalText([]byte(cfg.Level))
 	if err != nil {
 		return err
 	}
 
+	atomicLevel := zap.NewAtomicLevelAt(lvl)
+
 	logConfig := zap.Config{
-		Level:       zap.NewAtomicLevelAt(level),
+		Level:       atomicLevel,
 		Encoding:    cfg.Encoding,
 		OutputPaths: cfg.OutputPaths,
 		EncoderConfig: zapcore.EncoderConfig{
@@ -55,10 +60,16 @@ func Initialize(cfg Config) error {
 	}
 
 	zapLog = logger
+	level = atomicLevel
 
 	return nil
 }
 
+// SetLevel changes the minimum enabled log level without rebuilding the logger.
+func SetLevel(lvl string) error {
+	return level.UnmarshalText([]byte(lvl))
+}
+
 // init initializes the logger with default configuration.
 func init() {
 	if err := Initialize(DefaultConfig()); err != nil {
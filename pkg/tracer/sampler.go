@@ -0,0 +1,43 @@
+package tracer
+
+import (
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newSampler returns the sampler of the tracer provider along with the
+// ratioSampler controlling it. Root spans are sampled at ratio; the others
+// follow their parent, so that a trace sampled upstream stays whole.
+func newSampler(ratio float64) (sdktrace.Sampler, *ratioSampler) {
+	root := newRatioSampler(ratio)
+
+	return sdktrace.ParentBased(root), root
+}
+
+// ratioSampler samples a fraction of traces. Unlike the SDK samplers its
+// ratio can be changed after the tracer provider has been created.
+type ratioSampler struct {
+	current atomic.Pointer[sdktrace.Sampler]
+}
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.setRatio(ratio)
+
+	return s
+}
+
+// setRatio replaces the sampling ratio for spans started from now on.
+func (s *ratioSampler) setRatio(ratio float64) {
+	sampler := sdktrace.TraceIDRatioBased(ratio)
+	s.current.Store(&sampler)
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return (*s.current.Load()).Description()
+}
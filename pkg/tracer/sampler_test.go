@@ -0,0 +1,40 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestNewSampler(t *testing.T) {
+	sampler, ratio := newSampler(0)
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler)).Tracer("test")
+
+	remote := func(flags oteltrace.TraceFlags) context.Context {
+		return oteltrace.ContextWithRemoteSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID{1},
+			SpanID:     oteltrace.SpanID{1},
+			TraceFlags: flags,
+			Remote:     true,
+		}))
+	}
+
+	_, span := tracer.Start(remote(oteltrace.FlagsSampled), "child")
+	assert.True(t, span.SpanContext().IsSampled(), "a sampled remote parent is followed")
+
+	ratio.setRatio(1)
+
+	_, span = tracer.Start(remote(0), "child")
+	assert.False(t, span.SpanContext().IsSampled(), "an unsampled remote parent is followed")
+
+	_, span = tracer.Start(context.Background(), "root")
+	assert.True(t, span.SpanContext().IsSampled(), "root spans use the ratio")
+
+	ratio.setRatio(0)
+
+	_, span = tracer.Start(context.Background(), "root")
+	assert.False(t, span.SpanContext().IsSampled())
+}
@@ -23,13 +23,14 @@ import (
 
 // TracerProvider holds the tracer provider instance and shutdown function.
 type TracerProvider struct {
-	provider *sdktrace.TracerProvider
-	tracer   oteltrace.Tracer
+	provider    *sdktrace.TracerProvider
+	tracer      oteltrace.Tracer
+	unsubscribe func()
 }
 
-// NewTracer initializes a new tracer provider with the given service name.
-func NewTracer() (*TracerProvider, error) {
-	serviceName := config.Get(config.APP_NAME)
+// NewTracer initializes a new tracer provider for the configured application.
+func NewTracer(cfg *config.Config) (*TracerProvider, error) {
+	serviceName := cfg.App.Name
 
 	// Create resource with service information
 	res, err := resource.Merge(
@@ -38,7 +39,7 @@ func NewTracer() (*TracerProvider, error) {
 			semconv.SchemaURL,
 			semconv.ServiceNameKey.String(serviceName),
 			semconv.ServiceVersionKey.String("1.0.0"),
-			semconv.DeploymentEnvironmentKey.String(config.Get(config.ENV)),
+			semconv.DeploymentEnvironmentKey.String(cfg.App.Env),
 		),
 	)
 	if err != nil {
@@ -46,14 +47,20 @@ func NewTracer() (*TracerProvider, error) {
 	}
 
 	// Create span processor based on environment
-	bsp, err := createBatchSpanProcessor()
+	bsp, err := createBatchSpanProcessor(cfg.App.Env, cfg.Tracing)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create span processor: %w", err)
 	}
 
+	// Follow sampling ratio changes on config reload
+	sampler, ratio := newSampler(cfg.Tracing.SampleRatio)
+	unsubscribe := config.OnChange([]string{config.OTEL_TRACES_SAMPLER_RATIO}, func(_, next *config.Config) {
+		ratio.setRatio(next.Tracing.SampleRatio)
+	})
+
 	// Create tracer provider
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(res),
 		sdktrace.WithSpanProcessor(bsp),
 	)
@@ -69,8 +76,9 @@ func NewTracer() (*TracerProvider, error) {
 	tracer := tp.Tracer(serviceName)
 
 	return &TracerProvider{
-		provider: tp,
-		tracer:   tracer,
+		provider:    tp,
+		tracer:      tracer,
+		unsubscribe: unsubscribe,
 	}, nil
 }
 
@@ -79,6 +87,8 @@ func (tp *TracerProvider) Shutdown(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	tp.unsubscribe()
+
 	if err := tp.provider.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown tracer provider: %w", err)
 	}
@@ -92,8 +102,8 @@ func (tp *TracerProvider) Tracer() oteltrace.Tracer {
 }
 
 // createBatchSpanProcessor creates a span processor based on the environment.
-func createBatchSpanProcessor() (sdktrace.SpanProcessor, error) {
-	if config.Get(config.ENV) == "local" {
+func createBatchSpanProcessor(env string, cfg config.Tracing) (sdktrace.SpanProcessor, error) {
+	if env == "local" {
 		exporter, err := stdout.New(stdout.WithPrettyPrint())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
@@ -102,7 +112,7 @@ func createBatchSpanProcessor() (sdktrace.SpanProcessor, error) {
 		return sdktrace.NewBatchSpanProcessor(exporter), nil
 	}
 
-	endpoint := config.Get(config.OTEL_EXPORTER_OTLP_ENDPOINT)
+	endpoint := cfg.OTLPEndpoint
 	if endpoint == "" {
 		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT is not set")
 	}
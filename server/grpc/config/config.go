@@ -1,17 +1,30 @@
 package config
 
+import (
+	"strconv"
+	"time"
+
+	appconfig "go-template/internal/config"
+)
+
 // Config holds the server configuration parameters.
 type Config struct {
-	Host     string // Host address to bind to
-	GRPCPort string // Port for gRPC server
-	HTTPPort string // Port for HTTP gateway server
+	Host            string            // Host address to bind to
+	GRPCPort        string            // Port for gRPC server
+	HTTPPort        string            // Port for HTTP gateway server
+	ShutdownTimeout time.Duration     // Time allowed for in-flight requests on shutdown
+	Reflection      bool              // Whether to register the gRPC reflection service
+	App             *appconfig.Config // Full application configuration
 }
 
-// NewConfig creates a new Config instance with the given parameters.
-func NewConfig(host, grpcPort, httpPort string) *Config {
+// NewConfig creates a new Config instance from the application configuration.
+func NewConfig(app *appconfig.Config) *Config {
 	return &Config{
-		Host:     host,
-		GRPCPort: grpcPort,
-		HTTPPort: httpPort,
+		Host:            app.Server.Host,
+		GRPCPort:        strconv.Itoa(app.Server.GRPCPort),
+		HTTPPort:        strconv.Itoa(app.Server.HTTPPort),
+		ShutdownTimeout: app.Server.ShutdownTimeout,
+		Reflection:      app.Server.GRPCReflection,
+		App:             app,
 	}
-} 
\ No newline at end of file
+}
@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	pbName "go-template/proto/gen/go/helloservice/v1/name"
+	pbUser "go-template/proto/gen/go/userservice/v1/user"
 )
 
 // Gateway represents the gRPC-Gateway server.
@@ -44,6 +45,10 @@ func (g *Gateway) Setup(ctx context.Context, host, grpcPort string) error {
 		return fmt.Errorf("failed to register gRPC gateway: %w", err)
 	}
 
+	if err := pbUser.RegisterUserServiceHandlerFromEndpoint(ctx, g.mux, grpcServerEndpoint, opts); err != nil {
+		return fmt.Errorf("failed to register user service gateway: %w", err)
+	}
+
 	return nil
 }
 
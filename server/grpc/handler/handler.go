@@ -1,9 +1,13 @@
 package handler
 
 import (
-	pbName "go-template/proto/gen/go/helloservice/v1/name"
+	"go-template/internal/password"
+	"go-template/internal/repository"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	pbName "go-template/proto/gen/go/helloservice/v1/name"
+	pbUser "go-template/proto/gen/go/userservice/v1/user"
 )
 
 type Metrics struct {
@@ -21,3 +25,18 @@ func NewHelloServer(metrics *Metrics) *HelloServer {
 		Metrics: metrics,
 	}
 }
+
+type UserServer struct {
+	pbUser.UnimplementedUserServiceServer
+	Users  repository.UserRepository
+	Hasher *password.Hasher
+	Policy *password.Policy
+}
+
+func NewUserServer(users repository.UserRepository, hasher *password.Hasher, policy *password.Policy) *UserServer {
+	return &UserServer{
+		Users:  users,
+		Hasher: hasher,
+		Policy: policy,
+	}
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/mail"
+	"strconv"
+
+	"go-template/internal/clients/db"
+	"go-template/internal/repository"
+	"go-template/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pbUser "go-template/proto/gen/go/userservice/v1/user"
+)
+
+const maxPageSize = 1000
+
+func (s *UserServer) CreateUser(ctx context.Context, in *pbUser.CreateUserRequest) (*pbUser.CreateUserResponse, error) {
+	u := repository.User{
+		Username: in.GetUsername(),
+		Email:    in.GetEmail(),
+	}
+
+	if err := validateUser(u); err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hashPassword(in.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	u.Password = hash
+
+	created, err := s.Users.Create(ctx, u)
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	return &pbUser.CreateUserResponse{User: toProtoUser(created)}, nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, in *pbUser.GetUserRequest) (*pbUser.GetUserResponse, error) {
+	u, err := s.Users.Get(ctx, in.GetId())
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	return &pbUser.GetUserResponse{User: toProtoUser(u)}, nil
+}
+
+func (s *UserServer) ListUsers(ctx context.Context, in *pbUser.ListUsersRequest) (*pbUser.ListUsersResponse, error) {
+	pageSize := int(in.GetPageSize())
+
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = repository.DefaultListLimit
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	afterID, err := decodePageToken(in.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	// Fetch one extra user to learn whether another page follows.
+	users, err := s.Users.List(ctx, repository.ListOptions{AfterID: afterID, Limit: pageSize + 1})
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	resp := &pbUser.ListUsersResponse{}
+
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		resp.NextPageToken = encodePageToken(users[len(users)-1].ID)
+	}
+
+	for _, u := range users {
+		resp.Users = append(resp.Users, toProtoUser(u))
+	}
+
+	return resp, nil
+}
+
+func (s *UserServer) UpdateUser(ctx context.Context, in *pbUser.UpdateUserRequest) (*pbUser.UpdateUserResponse, error) {
+	patch := in.GetUser()
+	if patch == nil {
+		return nil, status.Error(codes.InvalidArgument, "user is required")
+	}
+
+	paths := in.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		// Without a mask, update every field the caller set.
+		if patch.GetUsername() != "" {
+			paths = append(paths, "username")
+		}
+
+		if patch.GetEmail() != "" {
+			paths = append(paths, "email")
+		}
+
+		if patch.GetPassword() != "" {
+			paths = append(paths, "password")
+		}
+	}
+
+	// The stored user is written back below, so it must not come from a
+	// replica that may lag behind.
+	u, err := s.Users.Get(db.WithPrimary(ctx), patch.GetId())
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "username":
+			u.Username = patch.GetUsername()
+		case "email":
+			u.Email = patch.GetEmail()
+		case "password":
+			hash, err := s.hashPassword(patch.GetPassword())
+			if err != nil {
+				return nil, err
+			}
+
+			u.Password = hash
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask: unsupported field %q", path)
+		}
+	}
+
+	if err := validateUser(u); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.Users.Update(ctx, u)
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	return &pbUser.UpdateUserResponse{User: toProtoUser(updated)}, nil
+}
+
+func (s *UserServer) DeleteUser(ctx context.Context, in *pbUser.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.Users.Delete(ctx, in.GetId()); err != nil {
+		return nil, userError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// validateUser checks the fields every stored user must have.
+func validateUser(u repository.User) error {
+	switch {
+	case u.Username == "":
+		return status.Error(codes.InvalidArgument, "username is required")
+	case u.Email == "":
+		return status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+		return status.Error(codes.InvalidArgument, "email is not a valid address")
+	}
+
+	return nil
+}
+
+// hashPassword checks plain against the password policy and hashes it.
+func (s *UserServer) hashPassword(plain string) (string, error) {
+	if err := s.Policy.Check(plain); err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	hash, err := s.Hasher.Hash(plain)
+	if err != nil {
+		logger.Error("Failed to hash password", zap.Error(err))
+
+		return "", status.Error(codes.Internal, "internal error")
+	}
+
+	return hash, nil
+}
+
+// userError maps repository errors to gRPC status errors.
+func userError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrUsernameTaken), errors.Is(err, repository.ErrEmailTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	logger.Error("User repository error", zap.Error(err))
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// toProtoUser converts a stored user, leaving out the password.
+func toProtoUser(u repository.User) *pbUser.User {
+	return &pbUser.User{
+		Id:       u.ID,
+		Username: u.Username,
+		Email:    u.Email,
+	}
+}
+
+// Page tokens are opaque to clients; they carry the last ID of the page.
+func encodePageToken(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+func decodePageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(b), 10, 64)
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-template/internal/password"
+	"go-template/internal/repository"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pbUser "go-template/proto/gen/go/userservice/v1/user"
+)
+
+func newTestUserServer() *UserServer {
+	hasher := password.NewHasher(password.Params{Time: 1, Memory: 64, Threads: 1})
+
+	return NewUserServer(repository.NewMemoryUserRepository(), hasher, &password.Policy{MinLength: 6, MaxLength: 64})
+}
+
+func TestUserServer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestUserServer()
+
+	created, err := s.CreateUser(ctx, &pbUser.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), created.GetUser().GetId())
+	assert.Empty(t, created.GetUser().GetPassword())
+
+	_, err = s.CreateUser(ctx, &pbUser.CreateUserRequest{Username: "alice", Email: "other@example.com", Password: "secret"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = s.CreateUser(ctx, &pbUser.CreateUserRequest{Username: "bob", Email: "not-an-email", Password: "secret"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.CreateUser(ctx, &pbUser.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stored, err := s.Users.Get(ctx, created.GetUser().GetId())
+	require.NoError(t, err)
+
+	rehash, err := s.Hasher.Verify("secret", stored.Password)
+	require.NoError(t, err)
+	assert.False(t, rehash)
+
+	_, err = s.GetUser(ctx, &pbUser.GetUserRequest{Id: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	updated, err := s.UpdateUser(ctx, &pbUser.UpdateUserRequest{
+		User:       &pbUser.User{Id: 1, Username: "ignored", Email: "alice@example.org"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.GetUser().GetUsername())
+	assert.Equal(t, "alice@example.org", updated.GetUser().GetEmail())
+
+	_, err = s.UpdateUser(ctx, &pbUser.UpdateUserRequest{
+		User:       &pbUser.User{Id: 1},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.DeleteUser(ctx, &pbUser.DeleteUserRequest{Id: 1})
+	require.NoError(t, err)
+
+	_, err = s.DeleteUser(ctx, &pbUser.DeleteUserRequest{Id: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestUserServer_ListUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestUserServer()
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := s.CreateUser(ctx, &pbUser.CreateUserRequest{Username: name, Email: name + "@example.com", Password: "secret"})
+		require.NoError(t, err)
+	}
+
+	var names []string
+
+	token := ""
+
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3, "pagination did not terminate")
+
+		resp, err := s.ListUsers(ctx, &pbUser.ListUsersRequest{PageSize: 2, PageToken: token})
+		require.NoError(t, err)
+
+		for _, u := range resp.GetUsers() {
+			names = append(names, u.GetUsername())
+		}
+
+		if token = resp.GetNextPageToken(); token == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	_, err := s.ListUsers(ctx, &pbUser.ListUsersRequest{PageToken: "!"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUserServer_Gateway(t *testing.T) {
+	ctx := context.Background()
+	mux := runtime.NewServeMux()
+	require.NoError(t, pbUser.RegisterUserServiceHandlerServer(ctx, mux, newTestUserServer()))
+
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			method:   http.MethodPost,
+			path:     "/v1/users",
+			body:     `{"username":"alice","email":"alice@example.com","password":"secret"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"user":{"id":"1","username":"alice","email":"alice@example.com","password":""}}`,
+		},
+		{
+			method:   http.MethodPatch,
+			path:     "/v1/users/1",
+			body:     `{"username":"alicia"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"user":{"id":"1","username":"alicia","email":"alice@example.com","password":""}}`,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/v1/users",
+			wantCode: http.StatusOK,
+			wantBody: `{"users":[{"id":"1","username":"alicia","email":"alice@example.com","password":""}],"nextPageToken":""}`,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/v1/users/1",
+			wantCode: http.StatusOK,
+			wantBody: `{}`,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/v1/users/1",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
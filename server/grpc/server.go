@@ -8,8 +8,12 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
-	"time"
 
+	"go-template/internal/clients/db"
+	appconfig "go-template/internal/config"
+	"go-template/internal/password"
+	"go-template/internal/readiness"
+	"go-template/internal/repository"
 	"go-template/pkg/logger"
 	"go-template/pkg/metrics"
 	"go-template/pkg/tracer"
@@ -26,6 +30,7 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	pbName "go-template/proto/gen/go/helloservice/v1/name"
+	pbUser "go-template/proto/gen/go/userservice/v1/user"
 )
 
 // Server represents a gRPC server instance.
@@ -35,12 +40,14 @@ type Server struct {
 	shutdownChan chan os.Signal
 	tp           *tracer.TracerProvider
 	Metrics      *handler.Metrics
+	users        repository.UserRepository
 	health       *health.Health
 	gateway      *gateway.Gateway
 }
 
-// NewServer creates a new Server instance with the given configuration.
-func NewServer(cfg *config.Config, metrics *handler.Metrics) *Server {
+// NewServer creates a new Server instance with the given configuration,
+// serving users from the given repository.
+func NewServer(cfg *config.Config, metrics *handler.Metrics, users repository.UserRepository) *Server {
 	if cfg == nil {
 		return nil
 	}
@@ -49,6 +56,7 @@ func NewServer(cfg *config.Config, metrics *handler.Metrics) *Server {
 		config:       cfg,
 		shutdownChan: make(chan os.Signal, 1),
 		Metrics:      metrics,
+		users:        users,
 		health:       health.NewHealth(),
 		gateway:      gateway.NewGateway(),
 	}
@@ -62,7 +70,7 @@ func (s *Server) Start(ctx context.Context) error {
 		zap.String("http_port", s.config.HTTPPort))
 
 	// Initialize tracer
-	tp, err := tracer.NewTracer()
+	tp, err := tracer.NewTracer(s.config.App)
 	if err != nil {
 		return fmt.Errorf("failed to initialize tracer: %w", err)
 	}
@@ -92,7 +100,7 @@ func (s *Server) Start(ctx context.Context) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		httpServer.CreateHTPPServer(ctx, s.config.Host, s.config.HTTPPort, s.gateway.GetMux())
+		httpServer.CreateHTPPServer(ctx, s.config.App, s.gateway.GetMux())
 	}()
 
 	// Wait for shutdown signal or error
@@ -123,12 +131,25 @@ func (s *Server) initGRPCServer() error {
 	helloServer := handler.NewHelloServer(s.Metrics)
 	pbName.RegisterGreeterServiceServer(s.grpcServer, helloServer)
 
-	// Register health check service
+	policy, err := password.NewPolicy(s.config.App.Password)
+	if err != nil {
+		return err
+	}
+
+	hasher := password.NewHasher(password.ParamsFromConfig(s.config.App.Password))
+	userServer := handler.NewUserServer(s.users, hasher, policy)
+	pbUser.RegisterUserServiceServer(s.grpcServer, userServer)
+
+	// Register health check service, reporting NOT_SERVING while not ready
 	s.health.Register(s.grpcServer)
-	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	readiness.OnChange(s.setServingStatus)
+	ready, _ := readiness.Status()
+	s.setServingStatus(ready)
 
 	// Register reflection service
-	reflection.Register(s.grpcServer)
+	if s.config.Reflection {
+		reflection.Register(s.grpcServer)
+	}
 
 	addr := net.JoinHostPort(s.config.Host, s.config.GRPCPort)
 	logger.Info("gRPC server initialized", zap.String("address", addr))
@@ -136,6 +157,16 @@ func (s *Server) initGRPCServer() error {
 	return nil
 }
 
+// setServingStatus reports the process readiness through the health service.
+func (s *Server) setServingStatus(ready bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	s.health.SetServingStatus("", status)
+}
+
 // startGRPCServer starts the gRPC server in a goroutine.
 func (s *Server) startGRPCServer(wg *sync.WaitGroup, errChan chan<- error) {
 	wg.Add(1)
@@ -162,7 +193,7 @@ func (s *Server) startGRPCServer(wg *sync.WaitGroup, errChan chan<- error) {
 
 // gracefulShutdown handles graceful shutdown of the server.
 func (s *Server) gracefulShutdown(ctx context.Context) {
-	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(ctx, s.config.ShutdownTimeout)
 	defer cancel()
 
 	// Shutdown tracer provider
@@ -194,11 +225,19 @@ func (s *Server) gracefulShutdown(ctx context.Context) {
 }
 
 // CreateGRPCServer creates and starts the gRPC and HTTP servers with the given configuration.
-func CreateGRPCServer(ctx context.Context, host, grpcPort, httpPort string) {
-	cfg := config.NewConfig(host, grpcPort, httpPort)
+func CreateGRPCServer(ctx context.Context, appCfg *appconfig.Config) {
+	cfg := config.NewConfig(appCfg)
 	metrics := registerMetrics()
 
-	server := NewServer(cfg, metrics)
+	database, err := db.Open(ctx, appCfg.DB)
+	if err != nil {
+		logger.Fatal("Failed to open database", zap.Error(err))
+	}
+	defer database.Close()
+
+	users := repository.NewPostgresUserRepository(database)
+
+	server := NewServer(cfg, metrics, users)
 	if err := server.Start(ctx); err != nil {
 		logger.Fatal("Server error", zap.Error(err))
 	}
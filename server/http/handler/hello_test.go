@@ -6,32 +6,35 @@ import (
 	"net/url"
 	"testing"
 
-	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	httpclient "go-template/internal/clients/httpClient"
 )
 
 func TestHandler_Hello(t *testing.T) {
 	// Setup
-	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
-	c := e.NewContext(req, rec)
 
-	client := httpclient.NewClient(httpclient.ClientOptions{
-		BaseURL: &url.URL{Scheme: "https", Host: "hacker-news.firebaseio.com", Path: "v0/"},
-		// Sock5Proxy:         config.Get(config.SOCKS5_PROXY),
+	client, err := httpclient.NewClient(httpclient.ClientOptions{
+		BaseURL:            &url.URL{Scheme: "https", Host: "hacker-news.firebaseio.com", Path: "v0/"},
 		InsecureSkipVerify: false,
 	})
+	require.NoError(t, err)
 
 	h := Handler{
 		HTTPClient: client,
+		Metrics: &Metrics{
+			HelloCounter: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "hello_counter_test"}, []string{"hello"}),
+			HelloGauge:   prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "hello_gauge_test"}, []string{"hello"}),
+		},
 	}
 
+	h.Hello(rec, req)
+
 	// Assertions
-	if assert.NoError(t, h.Hello(c)) {
-		assert.Equal(t, http.StatusOK, rec.Code)
-		assert.Equal(t, "Hello, World!", rec.Body.String())
-	}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message":"Hello, World!"}`, rec.Body.String())
 }
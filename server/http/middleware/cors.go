@@ -1,17 +1,51 @@
 package middleware
 
 import (
+	"net/http"
+	"sync/atomic"
+
 	"github.com/go-chi/cors"
 )
 
 // DefaultCORS returns a CORS middleware with default settings
 func DefaultCORS() *cors.Cors {
-	return cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
+	return cors.New(corsOptions([]string{"*"}))
+}
+
+// corsOptions returns the default CORS options for the given allowed origins
+func corsOptions(origins []string) cors.Options {
+	return cors.Options{
+		AllowedOrigins:   origins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
+	}
+}
+
+// ReloadableCORS is a CORS middleware whose allowed origins can be replaced
+// while the server is running
+type ReloadableCORS struct {
+	current atomic.Pointer[cors.Cors]
+}
+
+// NewReloadableCORS returns a CORS middleware allowing the given origins
+func NewReloadableCORS(origins []string) *ReloadableCORS {
+	c := &ReloadableCORS{}
+	c.SetAllowedOrigins(origins)
+
+	return c
+}
+
+// SetAllowedOrigins replaces the allowed origins for subsequent requests
+func (c *ReloadableCORS) SetAllowedOrigins(origins []string) {
+	c.current.Store(cors.New(corsOptions(origins)))
+}
+
+// Handler applies the current CORS settings to each request
+func (c *ReloadableCORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.current.Load().Handler(next).ServeHTTP(w, r)
 	})
-} 
\ No newline at end of file
+}
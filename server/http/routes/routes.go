@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"go-template/internal/readiness"
 	"go-template/server/http/handler"
 	"go-template/server/http/middleware"
 	"go-template/server/http/types"
@@ -69,6 +70,22 @@ func SetupRoutes(r *chi.Mux, h *handler.Handler, gwMux *runtime.ServeMux) {
 		}
 	})
 
+	// Readiness endpoint, failing while the process must not receive traffic
+	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		resp := types.HealthResponse{Status: "ready"}
+
+		if ready, reason := readiness.Status(); !ready {
+			resp = types.HealthResponse{Status: "not ready", Reason: reason}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	})
+
 	// Application routes
 	r.Get("/", h.Hello)
 	r.Get("/withparam", middleware.WrapMetricHandler("", h.HelloWithParam).ServeHTTP)
@@ -4,10 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"time"
 
 	"go-template/internal/config"
 	"go-template/pkg/metrics"
@@ -32,9 +30,9 @@ type Server struct {
 
 // CreateHTTPServer initializes and starts an HTTP server with the given configuration.
 // It sets up middleware, routes, and handles graceful shutdown.
-func CreateHTPPServer(ctx context.Context, host, port string, gwMux *runtime.ServeMux) {
+func CreateHTPPServer(ctx context.Context, cfg *config.Config, gwMux *runtime.ServeMux) {
 	// Initialize tracer
-	tp, err := tracer.NewTracer()
+	tp, err := tracer.NewTracer(cfg)
 	if err != nil {
 		logger.Fatal("Failed to initialize tracer", zap.Error(err))
 	}
@@ -48,31 +46,79 @@ func CreateHTPPServer(ctx context.Context, host, port string, gwMux *runtime.Ser
 	r := chi.NewRouter()
 
 	// Setup middleware
-	setupMiddleware(r)
+	setupMiddleware(r, cfg)
 
 	// Create handler instance
-	h := createHandler()
+	h, err := createHandler(cfg.HTTPClient)
+	if err != nil {
+		logger.Fatal("Failed to create upstream HTTP client", zap.Error(err))
+	}
 
 	// Setup routes
 	routes.SetupRoutes(r, h, gwMux)
 
 	// Start server
-	startServer(ctx, r, host, port)
+	startServer(ctx, r, cfg.Server)
 }
 
-func createHandler() *handler.Handler {
-	client := httpclient.NewClient(httpclient.ClientOptions{
-		BaseURL: &url.URL{
-			Scheme: "https",
-			Host:   "hacker-news.firebaseio.com",
-			Path:   "v0/",
-		},
+func createHandler(cfg config.HTTPClient) (*handler.Handler, error) {
+	minVersion, err := httpclient.ParseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache httpclient.Cache
+	if cfg.CacheEntries > 0 {
+		cache = httpclient.NewLRUCache(cfg.CacheEntries)
+	}
+
+	client, err := httpclient.NewClient(httpclient.ClientOptions{
+		BaseURL:            cfg.BaseURL,
+		Socks5Proxy:        cfg.Socks5Proxy,
+		HTTPProxy:          cfg.HTTPProxy,
+		NoProxy:            cfg.NoProxy,
 		InsecureSkipVerify: false,
+		RetryPolicy:        httpclient.DefaultRetryPolicy(),
+		Breaker:            httpclient.DefaultBreakerOptions(),
+		Limit: httpclient.LimitOptions{
+			Rate:        cfg.RateLimit,
+			Burst:       cfg.RateBurst,
+			MaxInFlight: cfg.MaxInFlight,
+		},
+		TLS: httpclient.TLSOptions{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			CAFiles:      cfg.TLSCAFiles,
+			MinVersion:   minVersion,
+			ServerName:   cfg.TLSServerName,
+			PinnedSHA256: cfg.TLSPins,
+		},
+		Cache: cache,
+		Timeouts: httpclient.Timeouts{
+			Call:    cfg.Timeout,
+			Attempt: cfg.AttemptTimeout,
+		},
+		Hedge: httpclient.HedgeOptions{
+			Enabled:  cfg.Hedging,
+			MinDelay: cfg.HedgeMinDelay,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Follow upstream limit changes on config reload
+	config.OnChange([]string{config.UPSTREAM_RATE_LIMIT, config.UPSTREAM_RATE_BURST, config.UPSTREAM_MAX_IN_FLIGHT}, func(_, next *config.Config) {
+		client.SetLimit(httpclient.LimitOptions{
+			Rate:        next.HTTPClient.RateLimit,
+			Burst:       next.HTTPClient.RateBurst,
+			MaxInFlight: next.HTTPClient.MaxInFlight,
+		})
 	})
 
 	metrics := registerMetrics()
 
-	return handler.NewHandler(client, metrics)
+	return handler.NewHandler(client, metrics), nil
 }
 
 func registerMetrics() *handler.Metrics {
@@ -85,8 +131,8 @@ func registerMetrics() *handler.Metrics {
 }
 
 // setupMiddleware configures all middleware for the server.
-func setupMiddleware(r *chi.Mux) {
-	appName := config.Get(config.APP_NAME)
+func setupMiddleware(r *chi.Mux, cfg *config.Config) {
+	appName := cfg.App.Name
 
 	// Add tracing middleware
 	r.Use(func(next http.Handler) http.Handler {
@@ -105,8 +151,12 @@ func setupMiddleware(r *chi.Mux) {
 	// Add logger middleware
 	r.Use(chimiddleware.Logger)
 
-	// Add CORS middleware
-	r.Use(middleware.DefaultCORS().Handler)
+	// Add CORS middleware, following allowed origin changes on config reload
+	corsMiddleware := middleware.NewReloadableCORS(cfg.Server.CORSOrigins)
+	config.OnChange([]string{config.CORS_ALLOWED_ORIGINS}, func(_, next *config.Config) {
+		corsMiddleware.SetAllowedOrigins(next.Server.CORSOrigins)
+	})
+	r.Use(corsMiddleware.Handler)
 }
 
 func otelReqFilter(req *http.Request) bool {
@@ -114,13 +164,13 @@ func otelReqFilter(req *http.Request) bool {
 }
 
 // startServer starts the HTTP server and handles graceful shutdown.
-func startServer(ctx context.Context, r *chi.Mux, host, port string) {
+func startServer(ctx context.Context, r *chi.Mux, cfg config.Server) {
 	// Create server context with cancellation
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
 	server := &http.Server{
-		Addr:    host + ":" + port,
+		Addr:    cfg.HTTPAddr(),
 		Handler: r,
 	}
 
@@ -136,8 +186,8 @@ func startServer(ctx context.Context, r *chi.Mux, host, port string) {
 	<-ctx.Done()
 	logger.Info("Shutting down HTTP server...")
 
-	// Give the server 10 seconds to complete pending requests
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Give the server the configured timeout to complete pending requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
@@ -1,8 +1,9 @@
 package types
 
-// HealthResponse represents the health check response
+// HealthResponse represents the health and readiness check response
 type HealthResponse struct {
 	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // HelloResponse represents the hello endpoint response